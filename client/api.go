@@ -0,0 +1,31 @@
+package wgc
+
+import (
+	"context"
+	"io"
+)
+
+// API is the subset of the WANGuard API client used by the collectors.
+// Besides *Client it can be implemented by decorators (caching, recording,
+// fan-out to several consoles) or by in-memory fakes in tests.
+type API interface {
+	// GetContext fetches path and returns the raw JSON response, which must not be modified
+	GetContext(ctx context.Context, path string) ([]byte, error)
+	// GetParsed fetches path and decodes the JSON response into obj
+	GetParsed(path string, obj interface{}) error
+	// GetParsedContext is like GetParsed but aborts once ctx is done
+	GetParsedContext(ctx context.Context, path string, obj interface{}) error
+}
+
+// StreamAPI is implemented by APIs that can decode a response while it is read, instead
+// of holding the whole body in memory first
+type StreamAPI interface {
+	// StreamContext fetches path and calls decode with the response body. Errors
+	// returned by decode are returned as is.
+	StreamContext(ctx context.Context, path string, decode func(io.Reader) error) error
+}
+
+var (
+	_ API       = (*Client)(nil)
+	_ StreamAPI = (*Client)(nil)
+)
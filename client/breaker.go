@@ -0,0 +1,184 @@
+package wgc
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tomvil/wanguard_exporter/logging"
+)
+
+// ErrCircuitOpen is returned without contacting the API while the circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open, WANGuard API requests are suspended")
+
+// CircuitBreakerPolicy controls when the client stops sending requests to a failing API
+type CircuitBreakerPolicy struct {
+	// FailureThreshold is the number of consecutive failed requests that opens the circuit; 0 disables the breaker
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before a single probe request is let through
+	OpenTimeout time.Duration
+}
+
+// DefaultCircuitBreakerPolicy is used by clients created without WithCircuitBreaker
+var DefaultCircuitBreakerPolicy = CircuitBreakerPolicy{
+	FailureThreshold: 5,
+	OpenTimeout:      30 * time.Second,
+}
+
+// WithCircuitBreaker overrides the DefaultCircuitBreakerPolicy of a Client
+func WithCircuitBreaker(policy CircuitBreakerPolicy) Option {
+	return func(c *Client) error {
+		if policy.FailureThreshold < 0 {
+			return errors.New("circuit breaker failure threshold must not be negative")
+		}
+		if policy.FailureThreshold > 0 && policy.OpenTimeout <= 0 {
+			return errors.New("circuit breaker open timeout must be positive")
+		}
+		c.breakerPolicy = policy
+		return nil
+	}
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitHalfOpen
+	circuitOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitHalfOpen:
+		return "half_open"
+	case circuitOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker counts consecutive API outages. Once FailureThreshold is reached
+// it fails requests fast for OpenTimeout, then lets one probe request through
+// (half-open) whose outcome decides whether the circuit closes or opens again.
+type circuitBreaker struct {
+	policy CircuitBreakerPolicy
+	target string
+	now    func() time.Time
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(policy CircuitBreakerPolicy, target string) *circuitBreaker {
+	if policy.FailureThreshold == 0 {
+		return nil
+	}
+
+	apiCircuitState.WithLabelValues(target).Set(float64(circuitClosed))
+
+	return &circuitBreaker{
+		policy: policy,
+		target: target,
+		now:    time.Now,
+	}
+}
+
+// allow returns ErrCircuitOpen if the request must not be sent
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == circuitOpen {
+		if b.now().Sub(b.openedAt) < b.policy.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		b.transition(circuitHalfOpen)
+	}
+
+	if b.state == circuitHalfOpen {
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+
+	return nil
+}
+
+// record feeds the outcome of a request allowed by allow back into the breaker.
+// Requests aborted by the caller tell nothing about the API and are not counted.
+func (b *circuitBreaker) record(err error, aborted bool) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == circuitHalfOpen {
+		b.probing = false
+		switch {
+		case aborted:
+		case isOutage(err):
+			b.open()
+		default:
+			b.failures = 0
+			b.transition(circuitClosed)
+		}
+		return
+	}
+
+	if aborted {
+		return
+	}
+
+	if !isOutage(err) {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == circuitClosed && b.failures >= b.policy.FailureThreshold {
+		b.open()
+	}
+}
+
+func (b *circuitBreaker) open() {
+	b.openedAt = b.now()
+	b.transition(circuitOpen)
+}
+
+func (b *circuitBreaker) transition(to circuitState) {
+	if b.state == to {
+		return
+	}
+
+	logging.Warn("WANGuard API circuit breaker for %s changed from %s to %s", b.target, b.state, to)
+	apiCircuitTransitions.WithLabelValues(b.target, b.state.String(), to.String()).Inc()
+	apiCircuitState.WithLabelValues(b.target).Set(float64(to))
+	b.state = to
+}
+
+// isOutage reports whether err indicates that the API itself is unavailable,
+// as opposed to a request the API answered but rejected
+func isOutage(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 || apiErr.StatusCode == 429
+	}
+
+	var netErr *NetworkError
+	return errors.As(err, &netErr)
+}
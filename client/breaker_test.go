@@ -0,0 +1,86 @@
+package wgc
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(CircuitBreakerPolicy{FailureThreshold: 2, OpenTimeout: time.Minute}, "breaker-test")
+	b.now = func() time.Time { return now }
+
+	outage := &APIError{Endpoint: "test", StatusCode: http.StatusServiceUnavailable}
+	for i := 0; i < 2; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("Expected closed circuit, got %v", err)
+		}
+		b.record(outage, false)
+	}
+
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("Expected probe request to be allowed, got %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected only one probe while half-open, got %v", err)
+	}
+
+	b.record(nil, false)
+	if b.state != circuitClosed {
+		t.Errorf("Expected closed circuit after successful probe, got %s", b.state)
+	}
+}
+
+func TestCircuitBreakerIgnoresClientErrorsAndAborts(t *testing.T) {
+	b := newCircuitBreaker(CircuitBreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Minute}, "breaker-test")
+
+	b.record(&APIError{Endpoint: "test", StatusCode: http.StatusNotFound}, false)
+	b.record(errors.New("context canceled"), true)
+
+	if b.state != circuitClosed {
+		t.Errorf("Expected closed circuit, got %s", b.state)
+	}
+}
+
+func TestClientFailsFastWhenCircuitOpen(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false,
+		WithRetryPolicy(RetryPolicy{MaxRetries: 0, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}),
+		WithCircuitBreaker(CircuitBreakerPolicy{FailureThreshold: 2, OpenTimeout: time.Minute}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	for i := 0; i < 5; i++ {
+		_, err = client.Get("test")
+	}
+
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("Expected 2 requests before the circuit opened, got %d", got)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	if b := newCircuitBreaker(CircuitBreakerPolicy{}, "breaker-test"); b != nil {
+		t.Error("Expected nil breaker when the failure threshold is 0")
+	}
+}
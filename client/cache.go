@@ -0,0 +1,182 @@
+package wgc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheSweepInterval is how often expired entries are purged from the cache
+const cacheSweepInterval = time.Minute
+
+// CachePolicy sets how long responses of matching endpoints are served from the cache.
+// Pattern is matched with path.Match against the normalized endpoint template
+// (e.g. "license_manager", "responses/{id}/actions" or "bgp_connectors/*").
+// A TTL of 0 disables caching for the matching endpoints.
+type CachePolicy struct {
+	Pattern string
+	TTL     time.Duration
+}
+
+// ParseCachePolicies parses a comma separated list of pattern=ttl pairs,
+// e.g. "license_manager=1h,responses/{id}/actions=10m,sensor_live_stats=0"
+func ParseCachePolicies(s string) ([]CachePolicy, error) {
+	var policies []CachePolicy
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		pattern, ttl, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid cache policy %q, expected pattern=ttl", item)
+		}
+
+		policy, err := newCachePolicy(strings.TrimSpace(pattern), strings.TrimSpace(ttl))
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+func newCachePolicy(pattern, ttl string) (CachePolicy, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return CachePolicy{}, fmt.Errorf("invalid cache pattern %q: %w", pattern, err)
+	}
+
+	d, err := time.ParseDuration(ttl)
+	if err != nil || d < 0 {
+		return CachePolicy{}, fmt.Errorf("invalid cache TTL %q for %s", ttl, pattern)
+	}
+
+	return CachePolicy{Pattern: pattern, TTL: d}, nil
+}
+
+// WithCache enables the in-memory response cache for endpoints matching one of the
+// policies. The first matching policy wins; endpoints matching none are never cached.
+func WithCache(policies []CachePolicy) Option {
+	return func(c *Client) error {
+		for _, p := range policies {
+			if _, err := path.Match(p.Pattern, ""); err != nil {
+				return fmt.Errorf("invalid cache pattern %q: %w", p.Pattern, err)
+			}
+			if p.TTL < 0 {
+				return errors.New("cache TTL must not be negative")
+			}
+		}
+		c.cachePolicies = policies
+		return nil
+	}
+}
+
+type bypassCacheKey struct{}
+
+// BypassCache returns a context whose requests skip the response cache and always
+// hit the API. Fresh responses are still stored for later callers.
+func BypassCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassCacheKey{}).(bool)
+	return bypass
+}
+
+type cacheEntry struct {
+	body     []byte
+	template string
+	expires  time.Time
+}
+
+// responseCache stores successful response bodies keyed by request URL
+type responseCache struct {
+	policies []CachePolicy
+	target   string
+	now      func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]cacheEntry
+	lastSweep time.Time
+}
+
+func newResponseCache(policies []CachePolicy, target string) *responseCache {
+	if len(policies) == 0 {
+		return nil
+	}
+
+	return &responseCache{
+		policies:  policies,
+		target:    target,
+		now:       time.Now,
+		entries:   make(map[string]cacheEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// ttl returns the TTL configured for an endpoint template, 0 if it is not cached
+func (rc *responseCache) ttl(template string) time.Duration {
+	if rc == nil {
+		return 0
+	}
+
+	for _, p := range rc.policies {
+		if ok, _ := path.Match(p.Pattern, template); ok {
+			return p.TTL
+		}
+	}
+
+	return 0
+}
+
+// get returns a copy of the cached body for key if it has not expired yet
+func (rc *responseCache) get(key, template string) ([]byte, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	entry, ok := rc.entries[key]
+	if ok && rc.now().After(entry.expires) {
+		delete(rc.entries, key)
+		apiCacheEvictions.WithLabelValues(rc.target, template).Inc()
+		ok = false
+	}
+
+	if !ok {
+		apiCacheMisses.WithLabelValues(rc.target, template).Inc()
+		return nil, false
+	}
+
+	apiCacheHits.WithLabelValues(rc.target, template).Inc()
+	return append([]byte(nil), entry.body...), true
+}
+
+func (rc *responseCache) put(key, template string, body []byte, ttl time.Duration) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	now := rc.now()
+	rc.entries[key] = cacheEntry{
+		body:     append([]byte(nil), body...),
+		template: template,
+		expires:  now.Add(ttl),
+	}
+
+	// Purge expired entries now and then so that one-off URLs do not pile up
+	if now.Sub(rc.lastSweep) >= cacheSweepInterval {
+		rc.lastSweep = now
+		for k, e := range rc.entries {
+			if now.After(e.expires) {
+				delete(rc.entries, k)
+				apiCacheEvictions.WithLabelValues(rc.target, e.template).Inc()
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package wgc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseCachePolicies(t *testing.T) {
+	policies, err := ParseCachePolicies("license_manager=1h, responses/{id}/actions=10m,sensor_live_stats=0")
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	if len(policies) != 3 {
+		t.Fatalf("Expected 3 policies, got %d", len(policies))
+	}
+
+	if policies[1].Pattern != "responses/{id}/actions" || policies[1].TTL != 10*time.Minute {
+		t.Errorf("Unexpected policy: %+v", policies[1])
+	}
+
+	for _, invalid := range []string{"license_manager", "license_manager=soon", "[=1h", "x=-1s"} {
+		if _, err := ParseCachePolicies(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestCacheServesFreshResponses(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"test": "success"}`)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false, WithCache([]CachePolicy{
+		{Pattern: "responses/*/actions", TTL: time.Hour},
+		{Pattern: "sensor_live_stats", TTL: 0},
+	}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Get("responses/1/actions"); err != nil {
+			t.Fatalf(errMsgExpectedNoError, err)
+		}
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("Expected 1 request for cached endpoint, got %d", got)
+	}
+
+	if _, err := client.GetContext(BypassCache(context.Background()), "responses/1/actions"); err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("Expected bypass to hit the API, got %d requests", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get("sensor_live_stats"); err != nil {
+			t.Fatalf(errMsgExpectedNoError, err)
+		}
+	}
+	if got := atomic.LoadInt32(&requests); got != 4 {
+		t.Errorf("Expected uncached endpoint to hit the API every time, got %d requests", got)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	now := time.Now()
+	rc := newResponseCache([]CachePolicy{{Pattern: "license_manager", TTL: time.Minute}}, "cache-test")
+	rc.now = func() time.Time { return now }
+
+	rc.put("key", "license_manager", []byte("body"), rc.ttl("license_manager"))
+	if body, ok := rc.get("key", "license_manager"); !ok || string(body) != "body" {
+		t.Fatalf("Expected cache hit, got %q %v", body, ok)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := rc.get("key", "license_manager"); ok {
+		t.Error("Expected expired entry to be evicted")
+	}
+
+	if len(rc.entries) != 0 {
+		t.Errorf("Expected empty cache, got %d entries", len(rc.entries))
+	}
+}
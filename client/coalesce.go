@@ -0,0 +1,66 @@
+package wgc
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// flightCall is an upstream request shared by all callers asking for the same URL
+type flightCall struct {
+	done chan struct{}
+	body []byte
+	err  error
+}
+
+// flightGroup coalesces concurrent identical GET requests (singleflight semantics):
+// while a request for a URL is in flight, further callers wait for its result
+// instead of sending their own.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do runs fn once per key at a time. shared reports whether the result was
+// obtained by another caller's request.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() ([]byte, error)) (body []byte, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+
+		// The leader gave up because of its own context; this caller still has time left
+		if isAborted(call.err) && ctx.Err() == nil {
+			body, err := fn()
+			return body, false, err
+		}
+
+		return call.body, true, call.err
+	}
+
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.body, call.err = fn()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+
+	return call.body, false, call.err
+}
+
+func isAborted(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,119 @@
+package wgc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrentIdenticalRequestsAreCoalesced(t *testing.T) {
+	var requests int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			close(started)
+		}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"test": "success"}`)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false)
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	results := make([]Response, callers)
+	errs := make([]error, callers)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs[0] = client.GetParsed("bgp_connectors", &results[0])
+	}()
+
+	<-started
+	for i := 1; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = client.GetParsed("bgp_connectors", &results[i])
+		}(i)
+	}
+
+	// Give the followers time to join the in-flight request
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i := 0; i < callers; i++ {
+		if errs[i] != nil || results[i].Test != "success" {
+			t.Errorf("Caller %d: unexpected result %+v, %v", i, results[i], errs[i])
+		}
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("Expected 1 upstream request, got %d", got)
+	}
+}
+
+func TestFlightGroupFollowerOutlivesAbortedLeader(t *testing.T) {
+	var g flightGroup
+	leaderStarted := make(chan struct{})
+	leaderDone := make(chan struct{})
+
+	go func() {
+		defer close(leaderDone)
+		_, _, _ = g.do(context.Background(), "key", func() ([]byte, error) {
+			close(leaderStarted)
+			time.Sleep(20 * time.Millisecond)
+			return nil, context.DeadlineExceeded
+		})
+	}()
+
+	<-leaderStarted
+	body, shared, err := g.do(context.Background(), "key", func() ([]byte, error) {
+		return []byte("fresh"), nil
+	})
+	<-leaderDone
+
+	if err != nil || shared || string(body) != "fresh" {
+		t.Errorf("Expected follower to fetch on its own, got %q shared=%v err=%v", body, shared, err)
+	}
+}
+
+func TestFlightGroupFollowerContext(t *testing.T) {
+	var g flightGroup
+	leaderStarted := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	go func() {
+		_, _, _ = g.do(context.Background(), "key", func() ([]byte, error) {
+			close(leaderStarted)
+			<-release
+			return nil, nil
+		})
+	}()
+
+	<-leaderStarted
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, _, err := g.do(ctx, "key", func() ([]byte, error) { return nil, nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected follower to give up at its deadline, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package wgc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/tomvil/wanguard_exporter/logging"
+)
+
+// Compatibility adapts an API endpoint to a range of WANGuard versions
+type Compatibility struct {
+	// Endpoint is matched against endpoint templates like CachePolicy.Pattern
+	Endpoint string
+	// Since and Until bound the versions the rule applies to. Since is inclusive,
+	// Until exclusive, and a zero Version leaves that side unbounded.
+	Since Version
+	Until Version
+	// Unsupported marks the endpoint as unavailable on these versions: requests fail
+	// with an UnsupportedEndpointError and a warning is logged once
+	Unsupported bool
+	// Resource replaces the first path segment, for resources renamed between releases
+	Resource string
+	// Fields renames JSON object keys of responses, from the name used by these
+	// versions to the name used by TestedVersion
+	Fields map[string]string
+}
+
+// DefaultCompatibility holds the adapters for known differences between WANGuard
+// releases. The endpoints and fields used by the exporter are verified against
+// TestedVersion only, so it is empty until a difference is confirmed on a real console.
+var DefaultCompatibility []Compatibility
+
+// WithCompatibility replaces the DefaultCompatibility rules of a Client. Rules only
+// apply once the console version is known (see DetectVersion and StartVersionDetection);
+// the first matching rule wins.
+func WithCompatibility(rules []Compatibility) Option {
+	return func(c *Client) error {
+		for _, rule := range rules {
+			if _, err := path.Match(rule.Endpoint, ""); err != nil || rule.Endpoint == "" {
+				return fmt.Errorf("invalid compatibility endpoint pattern %q", rule.Endpoint)
+			}
+			if !rule.Until.IsZero() && rule.Since.Compare(rule.Until) >= 0 {
+				return fmt.Errorf("compatibility rule for %s has an empty version range", rule.Endpoint)
+			}
+			if strings.Contains(rule.Resource, "/") {
+				return fmt.Errorf("compatibility rule for %s renames more than one path segment", rule.Endpoint)
+			}
+		}
+		c.compatRules = rules
+		return nil
+	}
+}
+
+// applies reports whether the rule covers version
+func (r *Compatibility) applies(version Version) bool {
+	if version.Compare(r.Since) < 0 {
+		return false
+	}
+	return r.Until.IsZero() || version.Compare(r.Until) < 0
+}
+
+// compatWarnings remembers which endpoints were already warned about
+type compatWarnings struct {
+	warned sync.Map
+}
+
+// once logs the warning produced by msg the first time it is called for endpoint
+func (w *compatWarnings) once(endpoint string, msg func() string) {
+	if _, loaded := w.warned.LoadOrStore(endpoint, struct{}{}); !loaded {
+		logging.Warn("%s", msg())
+	}
+}
+
+// compatibility returns the rule for the endpoint template on the connected console, if any
+func (c *Client) compatibility(template string) (*Compatibility, Version) {
+	if len(c.compatRules) == 0 {
+		return nil, Version{}
+	}
+
+	version, known := c.Version()
+	if !known {
+		return nil, Version{}
+	}
+
+	for i := range c.compatRules {
+		rule := &c.compatRules[i]
+		if matched, _ := path.Match(rule.Endpoint, template); matched && rule.applies(version) {
+			return rule, version
+		}
+	}
+
+	return nil, version
+}
+
+// adaptRequest applies rule to fullURL, failing for endpoints unsupported on version
+func (c *Client) adaptRequest(rule *Compatibility, version Version, template string, fullURL *url.URL) (*url.URL, error) {
+	if rule.Unsupported {
+		c.compatWarnings.once(template, func() string {
+			return fmt.Sprintf("API endpoint %s is not supported by WANGuard %s, skipping it", template, version)
+		})
+		return nil, &UnsupportedEndpointError{Endpoint: template, Version: version}
+	}
+
+	if rule.Resource == "" {
+		return fullURL, nil
+	}
+
+	adapted := *fullURL
+	i := strings.Index(adapted.Path, "/wanguard-api/v1/") + len("/wanguard-api/v1/")
+	rest := strings.SplitN(adapted.Path[i:], "/", 2)
+	rest[0] = rule.Resource
+	adapted.Path = adapted.Path[:i] + strings.Join(rest, "/")
+	adapted.RawPath = ""
+
+	return &adapted, nil
+}
+
+// adaptResponse renames the fields of body according to rule
+func adaptResponse(rule *Compatibility, body []byte) ([]byte, error) {
+	if rule == nil || len(rule.Fields) == 0 {
+		return body, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	return json.Marshal(renameFields(doc, rule.Fields))
+}
+
+// renameFields recursively renames the keys of all JSON objects in v
+func renameFields(v interface{}, fields map[string]string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		renamed := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			if name, ok := fields[key]; ok {
+				key = name
+			}
+			renamed[key] = renameFields(value, fields)
+		}
+		return renamed
+	case []interface{}:
+		for i := range v {
+			v[i] = renameFields(v[i], fields)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// warnNotFound logs once per endpoint that the console does not know it, which
+// usually means the endpoint is not available in the connected WANGuard version
+func (c *Client) warnNotFound(template string, err error) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		return
+	}
+
+	c.compatWarnings.once(template, func() string {
+		running := "this WANGuard version"
+		if version, known := c.Version(); known {
+			running = "WANGuard " + version.String()
+		}
+		return fmt.Sprintf("API endpoint %s returned 404 on %s (tested with %s), it may not be supported by this version", template, running, TestedVersion)
+	})
+}
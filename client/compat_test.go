@@ -0,0 +1,131 @@
+package wgc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newVersionedServer serves license_manager with the given version and echoes
+// the request path in the "path" field of every other response
+func newVersionedServer(t *testing.T, version string, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body := `{"software_version": "` + version + `"}`
+		if !strings.HasSuffix(r.URL.Path, "/license_manager") {
+			atomic.AddInt32(requests, 1)
+			body = `[{"path": "` + r.URL.Path + `", "prefix_v2": "10.0.0.0/8", "nested": {"prefix_v2": 1}}]`
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+}
+
+func TestCompatibilityUnsupportedEndpoint(t *testing.T) {
+	var requests int32
+	server := newVersionedServer(t, "8.1-5", &requests)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false, WithCompatibility([]Compatibility{
+		{Endpoint: "sensor_live_tops", Until: Version{8, 2, 0}, Unsupported: true},
+	}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	if _, err := client.DetectVersion(context.Background()); err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	_, err = client.Get("sensor_live_tops?sensor_id=1")
+	var unsupported *UnsupportedEndpointError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("Expected UnsupportedEndpointError, got %v", err)
+	}
+	if unsupported.Endpoint != "sensor_live_tops" || unsupported.Version != (Version{8, 1, 5}) {
+		t.Errorf("Unexpected error: %v", unsupported)
+	}
+	if IsRetryable(err) {
+		t.Error("Expected unsupported endpoints not to be retried")
+	}
+	if got := atomic.LoadInt32(&requests); got != 0 {
+		t.Errorf("Expected no request for an unsupported endpoint, got %d", got)
+	}
+
+	if _, err := client.Get("sensors"); err != nil {
+		t.Errorf("Expected other endpoints to be unaffected, got %v", err)
+	}
+}
+
+func TestCompatibilityRenames(t *testing.T) {
+	var requests int32
+	server := newVersionedServer(t, "9.0-1", &requests)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false, WithCompatibility([]Compatibility{
+		{Endpoint: "anomalies", Until: Version{9, 0, 0}, Unsupported: true},
+		{Endpoint: "anomalies", Since: Version{9, 0, 0}, Resource: "anomaly_reports", Fields: map[string]string{"prefix_v2": "prefix"}},
+	}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	if _, err := client.DetectVersion(context.Background()); err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	var got []struct {
+		Path   string
+		Prefix string
+		Nested struct {
+			Prefix int
+		}
+	}
+	if err := client.GetParsed("anomalies?status=Active", &got); err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	if len(got) != 1 || !strings.HasSuffix(got[0].Path, "/wanguard-api/v1/anomaly_reports") {
+		t.Fatalf("Expected the renamed resource to be requested, got %+v", got)
+	}
+	if got[0].Prefix != "10.0.0.0/8" || got[0].Nested.Prefix != 1 {
+		t.Errorf("Expected fields to be renamed, got %+v", got[0])
+	}
+}
+
+func TestCompatibilityIgnoredForOtherVersions(t *testing.T) {
+	var requests int32
+	server := newVersionedServer(t, "8.3-21", &requests)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false, WithCompatibility([]Compatibility{
+		{Endpoint: "anomalies", Since: Version{9, 0, 0}, Unsupported: true},
+	}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	if _, err := client.DetectVersion(context.Background()); err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	if _, err := client.Get("anomalies"); err != nil {
+		t.Errorf("Expected rule for newer versions to be ignored, got %v", err)
+	}
+}
+
+func TestWithCompatibilityValidation(t *testing.T) {
+	invalid := [][]Compatibility{
+		{{Endpoint: ""}},
+		{{Endpoint: "[", Unsupported: true}},
+		{{Endpoint: "anomalies", Since: Version{9, 0, 0}, Until: Version{8, 0, 0}}},
+		{{Endpoint: "anomalies", Resource: "a/b"}},
+	}
+	for _, rules := range invalid {
+		if _, err := NewClient("http://localhost", "u", "p", false, WithCompatibility(rules)); err == nil {
+			t.Errorf("Expected error for %+v", rules)
+		}
+	}
+}
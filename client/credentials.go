@@ -0,0 +1,157 @@
+package wgc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tomvil/wanguard_exporter/logging"
+)
+
+// credentialCheckInterval bounds how often the credential files are checked for changes
+const credentialCheckInterval = 5 * time.Second
+
+// WithCredentialFiles makes the client read the API username and/or password from
+// files instead of using the values passed to NewClient. The files are re-read when
+// they change and whenever the API rejects the credentials, so secrets rotated by
+// Kubernetes or Vault agents are picked up without restarting the exporter.
+func WithCredentialFiles(usernameFile, passwordFile string) Option {
+	return func(c *Client) error {
+		if usernameFile == "" && passwordFile == "" {
+			return nil
+		}
+
+		c.credFiles = &credentialFiles{usernameFile: usernameFile, passwordFile: passwordFile}
+		if _, err := c.reloadCredentials(); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// credentialFiles tracks the files credentials are loaded from
+type credentialFiles struct {
+	usernameFile string
+	passwordFile string
+
+	mu        sync.Mutex
+	lastCheck time.Time
+	versions  map[string]fileVersion
+}
+
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// credentials returns the username and password to authenticate with,
+// reloading them first if one of the credential files changed
+func (c *Client) credentials() (string, string) {
+	if c.credFiles != nil && c.credFiles.changed() {
+		if _, err := c.reloadCredentials(); err != nil {
+			logging.Error("Failed to reload WANGuard API credentials, keeping the previous ones: %v", err)
+		}
+	}
+
+	c.credMu.RLock()
+	defer c.credMu.RUnlock()
+
+	return c.apiUsername, c.apiPassword
+}
+
+// changed reports whether a credential file was modified since it was last read.
+// Files are stat'ed at most once per credentialCheckInterval.
+func (f *credentialFiles) changed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if time.Since(f.lastCheck) < credentialCheckInterval {
+		return false
+	}
+	f.lastCheck = time.Now()
+
+	for _, file := range []string{f.usernameFile, f.passwordFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil || f.versions[file] != (fileVersion{info.ModTime(), info.Size()}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reloadCredentials reads the credential files and reports whether the credentials changed.
+// On failure the previous credentials stay in use.
+func (c *Client) reloadCredentials() (bool, error) {
+	if c.credFiles == nil {
+		return false, nil
+	}
+
+	target := c.GetSanitizedTarget()
+
+	username, usernameVersion, err := readCredentialFile(c.credFiles.usernameFile)
+	if err == nil {
+		var password string
+		var passwordVersion fileVersion
+		password, passwordVersion, err = readCredentialFile(c.credFiles.passwordFile)
+		if err == nil {
+			c.credFiles.mu.Lock()
+			c.credFiles.versions = map[string]fileVersion{
+				c.credFiles.usernameFile: usernameVersion,
+				c.credFiles.passwordFile: passwordVersion,
+			}
+			c.credFiles.mu.Unlock()
+
+			c.credMu.Lock()
+			defer c.credMu.Unlock()
+
+			if c.credFiles.usernameFile == "" {
+				username = c.apiUsername
+			}
+			if c.credFiles.passwordFile == "" {
+				password = c.apiPassword
+			}
+
+			changed := username != c.apiUsername || password != c.apiPassword
+			c.apiUsername, c.apiPassword = username, password
+
+			apiCredentialsReloadSuccess.WithLabelValues(target).Set(1)
+			apiCredentialsReloadTimestamp.WithLabelValues(target).SetToCurrentTime()
+			return changed, nil
+		}
+	}
+
+	apiCredentialsReloadSuccess.WithLabelValues(target).Set(0)
+	return false, err
+}
+
+// readCredentialFile returns the first line of file; an empty name yields no error
+func readCredentialFile(file string) (string, fileVersion, error) {
+	if file == "" {
+		return "", fileVersion{}, nil
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", fileVersion{}, fmt.Errorf("failed to read credential file: %w", err)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", fileVersion{}, fmt.Errorf("failed to read credential file: %w", err)
+	}
+
+	value := strings.TrimRight(string(content), "\r\n")
+	if value == "" {
+		return "", fileVersion{}, errors.New("credential file " + file + " is empty")
+	}
+
+	return value, fileVersion{info.ModTime(), info.Size()}, nil
+}
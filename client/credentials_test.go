@@ -0,0 +1,102 @@
+package wgc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func writeCredentialFile(t *testing.T, file, value string) {
+	if err := os.WriteFile(file, []byte(value+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCredentialFilesReloadOnUnauthorized(t *testing.T) {
+	var requests int32
+	var password atomic.Value
+	password.Store("old")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if _, p, _ := r.BasicAuth(); p != password.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"test": "success"}`)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	defer server.Close()
+
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	writeCredentialFile(t, passwordFile, "old")
+
+	client, err := NewClient(server.URL, "u", "", false, noRetries(), WithCredentialFiles("", passwordFile))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	if _, err := client.Get("test"); err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	// Rotate the secret on both ends; the client should recover on the 401
+	password.Store("new")
+	writeCredentialFile(t, passwordFile, "new")
+
+	atomic.StoreInt32(&requests, 0)
+	if _, err := client.Get("test"); err != nil {
+		t.Fatalf("Expected request to succeed after reloading credentials, got %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("Expected 2 requests (401 + retry), got %d", got)
+	}
+}
+
+func TestCredentialFilesReloadOnChange(t *testing.T) {
+	dir := t.TempDir()
+	usernameFile := filepath.Join(dir, "username")
+	passwordFile := filepath.Join(dir, "password")
+	writeCredentialFile(t, usernameFile, "admin")
+	writeCredentialFile(t, passwordFile, "secret")
+
+	client, err := NewClient("http://127.0.0.1", "ignored", "ignored", false, WithCredentialFiles(usernameFile, passwordFile))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	if u, p := client.credentials(); u != "admin" || p != "secret" {
+		t.Fatalf("Expected credentials from files, got %q/%q", u, p)
+	}
+
+	writeCredentialFile(t, passwordFile, "rotated")
+	client.credFiles.lastCheck = time.Time{}
+	if _, p := client.credentials(); p != "rotated" {
+		t.Errorf("Expected rotated password, got %q", p)
+	}
+
+	// A broken file keeps the previous credentials
+	if err := os.Remove(passwordFile); err != nil {
+		t.Fatal(err)
+	}
+	client.credFiles.lastCheck = time.Time{}
+	if _, p := client.credentials(); p != "rotated" {
+		t.Errorf("Expected previous password to be kept, got %q", p)
+	}
+}
+
+func TestCredentialFilesValidation(t *testing.T) {
+	if _, err := NewClient("http://127.0.0.1", "u", "", false, WithCredentialFiles("", filepath.Join(t.TempDir(), "missing"))); err == nil {
+		t.Error("Expected error for a missing password file")
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	writeCredentialFile(t, empty, "")
+	if _, err := NewClient("http://127.0.0.1", "u", "", false, WithCredentialFiles("", empty)); err == nil {
+		t.Error("Expected error for an empty password file")
+	}
+}
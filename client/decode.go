@@ -0,0 +1,141 @@
+package wgc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// errBodyTooLarge stops the reading of a responseBody at its limit
+var errBodyTooLarge = errors.New("response body exceeds the size limit")
+
+// responseBody reads a response body up to limit bytes and records why reading stopped,
+// so that oversized and interrupted bodies are told apart from malformed JSON
+type responseBody struct {
+	r     io.Reader
+	limit int64
+
+	n        int64
+	tooLarge bool
+	err      error
+}
+
+func (b *responseBody) Read(p []byte) (int, error) {
+	// One byte more than the limit is read so that oversized responses fail loudly
+	// instead of being truncated into a confusing parse error
+	if remaining := b.limit + 1 - b.n; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	if b.n > b.limit {
+		b.tooLarge = true
+		return 0, errBodyTooLarge
+	}
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+// decodeJSON decodes exactly one JSON value from r into obj
+func decodeJSON(r io.Reader, obj interface{}) error {
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(obj); err != nil {
+		return err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after JSON value")
+	}
+
+	return nil
+}
+
+// DecodeArray decodes the JSON array read from r one element at a time and calls fn
+// for each of them, so large lists never have to be held in memory as a whole.
+// A JSON null is treated as an empty array. Errors returned by fn stop the decoding
+// and are returned as is.
+func DecodeArray[T any](r io.Reader, fn func(T) error) error {
+	dec := json.NewDecoder(r)
+
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected JSON array, got %v", token)
+	}
+
+	for dec.More() {
+		var elem T
+		if err := dec.Decode(&elem); err != nil {
+			return err
+		}
+		if err := fn(elem); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after JSON value")
+	}
+
+	return nil
+}
+
+// EachElement fetches path and calls fn for every element of the JSON array it returns.
+// With an API implementing StreamAPI, the elements are decoded one at a time while the
+// response is read (see DecodeArray).
+func EachElement[T any](ctx context.Context, api API, path string, fn func(T) error) error {
+	var fnErr, decodeErr error
+	decode := func(r io.Reader) error {
+		decodeErr = DecodeArray(r, func(elem T) error {
+			fnErr = fn(elem)
+			return fnErr
+		})
+		return decodeErr
+	}
+
+	var err error
+	if stream, ok := api.(StreamAPI); ok {
+		err = stream.StreamContext(ctx, path, decode)
+	} else {
+		var body []byte
+		if body, err = api.GetContext(ctx, path); err == nil {
+			err = decode(bytes.NewReader(body))
+		}
+	}
+
+	// Errors of the request itself are returned as is
+	if decodeErr == nil || !errors.Is(err, decodeErr) {
+		return err
+	}
+	if fnErr != nil {
+		return fnErr
+	}
+	if c, ok := api.(*Client); ok {
+		apiParseErrors.WithLabelValues(c.GetSanitizedTarget(), endpointTemplate(path)).Inc()
+	}
+	return fmt.Errorf("failed to parse JSON response: %w", err)
+}
+
+// decodeError wraps the error of the function reading a response body, as opposed to
+// the errors of the request itself
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string { return e.err.Error() }
+
+func (e *decodeError) Unwrap() error { return e.err }
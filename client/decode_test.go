@@ -0,0 +1,180 @@
+package wgc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDecodeArray(t *testing.T) {
+	var got []string
+	err := DecodeArray(strings.NewReader(`[{"test": "a"}, {"test": "b"}]`), func(r Response) error {
+		got = append(got, r.Test)
+		return nil
+	})
+	if err != nil || strings.Join(got, ",") != "a,b" {
+		t.Errorf("Expected elements a,b, got %v (%v)", got, err)
+	}
+
+	if err := DecodeArray(strings.NewReader(`null`), func(r Response) error {
+		t.Error("Expected no elements for null")
+		return nil
+	}); err != nil {
+		t.Errorf(errMsgExpectedNoError, err)
+	}
+
+	for _, invalid := range []string{`{"test": "a"}`, `[{"test": "a"}`, `[{"test": 1}]`, `[] []`} {
+		if err := DecodeArray(strings.NewReader(invalid), func(Response) error { return nil }); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = DecodeArray(strings.NewReader(`[{}, {}, {}]`), func(Response) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected decoding to stop at the first callback error, got %v after %d calls", err, calls)
+	}
+}
+
+func TestDecodeJSONRejectsTrailingData(t *testing.T) {
+	var r Response
+	if err := decodeJSON(strings.NewReader(`{"test": "a"} {"test": "b"}`), &r); err == nil {
+		t.Error("Expected error for trailing data")
+	}
+}
+
+func TestEachElement(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`[{"test": "a"}, {"test": "b"}, {"test": "c"}]`)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false)
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	count := 0
+	if err := EachElement(context.Background(), client, "anomalies", func(Response) error {
+		count++
+		return nil
+	}); err != nil || count != 3 {
+		t.Errorf("Expected 3 elements, got %d (%v)", count, err)
+	}
+
+	if err := EachElement(context.Background(), client, "anomalies", func(struct{ Test int }) error {
+		return nil
+	}); err == nil || !strings.Contains(err.Error(), "failed to parse JSON response") {
+		t.Errorf("Expected parse error, got %v", err)
+	}
+}
+
+func TestEachElementStreams(t *testing.T) {
+	firstDecoded := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`[{"test": "a"},`)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+		w.(http.Flusher).Flush()
+
+		// The rest of the list is only sent once the first element was decoded
+		select {
+		case <-firstDecoded:
+		case <-time.After(5 * time.Second):
+			t.Error("Expected the first element to be decoded before the response ended")
+		}
+		if _, err := w.Write([]byte(` {"test": "b"}]`)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false)
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	var got []string
+	if err := EachElement(context.Background(), client, "anomalies", func(r Response) error {
+		if got = append(got, r.Test); len(got) == 1 {
+			close(firstDecoded)
+		}
+		return nil
+	}); err != nil || strings.Join(got, ",") != "a,b" {
+		t.Errorf("Expected elements a,b, got %v (%v)", got, err)
+	}
+}
+
+func TestEachElementResponseTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.(http.Flusher).Flush()
+		if _, err := w.Write([]byte(`[{"test": "a"}, {"test": "` + strings.Repeat("x", 100) + `"}]`)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false, noRetries(), WithMaxResponseSize(64))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	err = EachElement(context.Background(), client, "firewall_rules", func(Response) error { return nil })
+	var tooLarge *ResponseTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("Expected ResponseTooLargeError, got %v", err)
+	}
+}
+
+func TestResponseTooLarge(t *testing.T) {
+	for name, chunked := range map[string]bool{"content-length": false, "chunked": true} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if chunked {
+					w.(http.Flusher).Flush()
+				}
+				if _, err := w.Write([]byte(`{"test": "` + strings.Repeat("x", 100) + `"}`)); err != nil {
+					t.Errorf(errMsgExpectedNoError, err)
+				}
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL, "u", "p", false, noRetries(), WithMaxResponseSize(64))
+			if err != nil {
+				t.Fatalf(errMsgExpectedNoError, err)
+			}
+
+			var response Response
+			err = client.GetParsed("sensor_live_stats", &response)
+			var tooLarge *ResponseTooLargeError
+			if !errors.As(err, &tooLarge) || tooLarge.Limit != 64 {
+				t.Fatalf("Expected ResponseTooLargeError, got %v", err)
+			}
+
+			oversize := apiResponseOversize.WithLabelValues(client.GetSanitizedTarget(), "sensor_live_stats")
+			if got := testutil.ToFloat64(oversize); got != 1 {
+				t.Errorf("Expected 1 oversize response, got %v", got)
+			}
+		})
+	}
+
+	if _, err := NewClient("http://127.0.0.1", "u", "p", false, WithMaxResponseSize(0)); err == nil {
+		t.Error("Expected error for a zero response size limit")
+	}
+}
@@ -0,0 +1,85 @@
+package wgc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// APIError is returned when the WANGuard API answers with a non-2xx status code
+type APIError struct {
+	Endpoint   string
+	StatusCode int
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API returned status %d for %s", e.StatusCode, e.Endpoint)
+}
+
+// Unauthorized reports whether the API rejected the configured credentials
+func (e *APIError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// Retryable reports whether the status code indicates a transient condition
+func (e *APIError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// NetworkError is returned when the API could not be reached or the connection
+// broke before a complete response was received
+type NetworkError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("HTTP request to %s failed: %v", e.Endpoint, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// ResponseTooLargeError is returned when a response body exceeds the configured size limit
+type ResponseTooLargeError struct {
+	Endpoint string
+	Limit    int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response from %s is larger than the %d bytes limit (see -api.max-response-size)", e.Endpoint, e.Limit)
+}
+
+// UnsupportedEndpointError is returned for endpoints a compatibility rule marks as
+// unavailable on the connected WANGuard version
+type UnsupportedEndpointError struct {
+	Endpoint string
+	Version  Version
+}
+
+func (e *UnsupportedEndpointError) Error() string {
+	return fmt.Sprintf("API endpoint %s is not supported by WANGuard %s", e.Endpoint, e.Version)
+}
+
+// IsRetryable reports whether err belongs to a class of failures that may
+// succeed when the same idempotent request is sent again
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+
+	var netErr *NetworkError
+	return errors.As(err, &netErr)
+}
+
+// IsUnauthorized reports whether err was caused by the API rejecting the credentials
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Unauthorized()
+}
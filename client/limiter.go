@@ -0,0 +1,174 @@
+package wgc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when a request could not get a slot from the client-side limiter in time
+var ErrRateLimited = errors.New("request rejected by the client-side API rate limiter")
+
+// RateLimit bounds the load the client puts on the WANGuard Console. It is shared
+// by all collectors using the same Client.
+type RateLimit struct {
+	// MaxInFlight is the maximum number of concurrent API requests; 0 means unlimited
+	MaxInFlight int
+	// RequestsPerSecond is the sustained request rate; 0 means unlimited
+	RequestsPerSecond float64
+	// Burst is the number of requests allowed at once before RequestsPerSecond applies
+	Burst int
+	// MaxWait is the longest a request may queue before it is rejected; 0 waits as long as its context allows
+	MaxWait time.Duration
+}
+
+// DefaultRateLimit is used by clients created without WithRateLimit
+var DefaultRateLimit = RateLimit{
+	MaxInFlight: 8,
+}
+
+// WithRateLimit overrides the DefaultRateLimit of a Client
+func WithRateLimit(limit RateLimit) Option {
+	return func(c *Client) error {
+		if limit.MaxInFlight < 0 || limit.RequestsPerSecond < 0 || limit.Burst < 0 || limit.MaxWait < 0 {
+			return errors.New("rate limit settings must not be negative")
+		}
+		c.rateLimit = limit
+		return nil
+	}
+}
+
+// limiter combines a concurrency semaphore with a token bucket
+type limiter struct {
+	target  string
+	maxWait time.Duration
+	slots   chan struct{}
+	bucket  *tokenBucket
+}
+
+func newLimiter(limit RateLimit, target string) *limiter {
+	if limit.MaxInFlight == 0 && limit.RequestsPerSecond == 0 {
+		return nil
+	}
+
+	l := &limiter{target: target, maxWait: limit.MaxWait}
+	if limit.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, limit.MaxInFlight)
+	}
+	if limit.RequestsPerSecond > 0 {
+		l.bucket = newTokenBucket(limit.RequestsPerSecond, limit.Burst)
+	}
+
+	return l
+}
+
+// acquire blocks until the request may be sent and returns a function releasing its slot
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	start := time.Now()
+	if l.maxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.maxWait)
+		defer cancel()
+	}
+
+	release, err := l.wait(ctx)
+	apiLimiterWait.WithLabelValues(l.target).Observe(time.Since(start).Seconds())
+	if err != nil {
+		apiLimiterRejected.WithLabelValues(l.target).Inc()
+		return nil, err
+	}
+
+	return release, nil
+}
+
+func (l *limiter) wait(ctx context.Context) (func(), error) {
+	release := func() {}
+
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+			release = func() { <-l.slots }
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: no free slot: %v", ErrRateLimited, ctx.Err())
+		}
+	}
+
+	if l.bucket != nil {
+		delay := l.bucket.reserve(time.Now())
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			l.bucket.cancel()
+			release()
+			return nil, fmt.Errorf("%w: rate exceeded", ErrRateLimited)
+		}
+
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				l.bucket.cancel()
+				release()
+				return nil, fmt.Errorf("%w: rate exceeded: %v", ErrRateLimited, ctx.Err())
+			}
+		}
+	}
+
+	return release, nil
+}
+
+// tokenBucket refills at rate tokens per second up to burst tokens.
+// Tokens may be reserved ahead of time, letting the balance go negative.
+type tokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long the caller has to wait before using it
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel gives back a token reserved by a request that was not sent
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	b.tokens++
+	b.mu.Unlock()
+}
@@ -0,0 +1,91 @@
+package wgc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterBoundsConcurrency(t *testing.T) {
+	var current, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+		w.Header().Set("Content-Type", "application/json")
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false, WithRateLimit(RateLimit{MaxInFlight: 2}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Distinct paths so that requests are not coalesced
+			if _, err := client.Get("sensor_live_tops?top=" + string(rune('a'+i))); err != nil {
+				t.Errorf(errMsgExpectedNoError, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&peak); got > 2 {
+		t.Errorf("Expected at most 2 concurrent requests, got %d", got)
+	}
+}
+
+func TestLimiterRejectsAfterMaxWait(t *testing.T) {
+	l := newLimiter(RateLimit{MaxInFlight: 1, MaxWait: 10 * time.Millisecond}, "limiter-test")
+
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	defer release()
+
+	if _, err := l.acquire(context.Background()); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(10, 2)
+	now := b.last
+
+	if d := b.reserve(now); d != 0 {
+		t.Errorf("Expected burst token without delay, got %s", d)
+	}
+	if d := b.reserve(now); d != 0 {
+		t.Errorf("Expected burst token without delay, got %s", d)
+	}
+	if d := b.reserve(now); d != 100*time.Millisecond {
+		t.Errorf("Expected 100ms delay once the burst is used, got %s", d)
+	}
+
+	b.cancel()
+	if d := b.reserve(now.Add(100 * time.Millisecond)); d != 0 {
+		t.Errorf("Expected refilled token without delay, got %s", d)
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	if l := newLimiter(RateLimit{}, "limiter-test"); l != nil {
+		t.Error("Expected nil limiter without limits")
+	}
+}
@@ -0,0 +1,177 @@
+package wgc
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics describing the health of the WANGuard API as seen by the client
+var (
+	wanguardAPIUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "wanguard_api_up",
+			Help: "Whether the WANGuard API is reachable (1 = up, 0 = down)",
+		},
+		[]string{"api_address"},
+	)
+
+	apiCircuitState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "wanguard_api_circuit_state",
+			Help: "State of the WANGuard API circuit breaker (0 = closed, 1 = half-open, 2 = open)",
+		},
+		[]string{"api_address"},
+	)
+
+	apiCircuitTransitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "wanguard_api_circuit_transitions_total",
+			Help: "Number of WANGuard API circuit breaker state transitions",
+		},
+		[]string{"api_address", "from", "to"},
+	)
+
+	apiRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "wanguard_api_request_duration_seconds",
+			Help:    "Duration of WANGuard API requests by endpoint",
+			Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"api_address", "endpoint"},
+	)
+
+	apiRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "wanguard_api_requests_total",
+			Help: "Number of WANGuard API requests by endpoint and HTTP status code",
+		},
+		[]string{"api_address", "endpoint", "code"},
+	)
+
+	apiResponseSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "wanguard_api_response_size_bytes",
+			Help:    "Size of successful WANGuard API response bodies by endpoint",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 8),
+		},
+		[]string{"api_address", "endpoint"},
+	)
+
+	apiResponseOversize = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "wanguard_api_response_oversize_total",
+			Help: "Number of WANGuard API responses rejected for exceeding the maximum response size by endpoint",
+		},
+		[]string{"api_address", "endpoint"},
+	)
+
+	apiParseErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "wanguard_api_parse_errors_total",
+			Help: "Number of WANGuard API responses that could not be parsed by endpoint",
+		},
+		[]string{"api_address", "endpoint"},
+	)
+
+	apiRequestsDeduplicated = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "wanguard_api_requests_deduplicated_total",
+			Help: "Number of WANGuard API requests answered by an identical request already in flight",
+		},
+		[]string{"api_address", "endpoint"},
+	)
+
+	apiLimiterWait = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "wanguard_api_limiter_wait_seconds",
+			Help:    "Time WANGuard API requests spent queued in the client-side rate limiter",
+			Buckets: []float64{.001, .01, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		},
+		[]string{"api_address"},
+	)
+
+	apiLimiterRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "wanguard_api_limiter_rejected_total",
+			Help: "Number of WANGuard API requests rejected by the client-side rate limiter",
+		},
+		[]string{"api_address"},
+	)
+
+	apiCredentialsReloadSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "wanguard_api_credentials_reload_success",
+			Help: "Whether the last reload of the WANGuard API credential files succeeded (1 = success, 0 = failure)",
+		},
+		[]string{"api_address"},
+	)
+
+	apiCredentialsReloadTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "wanguard_api_credentials_last_reload_success_timestamp_seconds",
+			Help: "Timestamp of the last successful reload of the WANGuard API credential files",
+		},
+		[]string{"api_address"},
+	)
+
+	apiSSHTunnelUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "wanguard_api_ssh_tunnel_up",
+			Help: "Whether the SSH tunnel to the WANGuard API is connected (1 = up, 0 = down)",
+		},
+		[]string{"api_address"},
+	)
+
+	apiSSHTunnelReconnects = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "wanguard_api_ssh_tunnel_reconnects_total",
+			Help: "Number of times the SSH tunnel to the WANGuard API was re-established",
+		},
+		[]string{"api_address"},
+	)
+
+	apiCacheHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "wanguard_api_cache_hits_total",
+			Help: "Number of WANGuard API responses served from the cache",
+		},
+		[]string{"api_address", "endpoint"},
+	)
+
+	apiCacheMisses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "wanguard_api_cache_misses_total",
+			Help: "Number of cacheable WANGuard API requests not found in the cache",
+		},
+		[]string{"api_address", "endpoint"},
+	)
+
+	apiCacheEvictions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "wanguard_api_cache_evictions_total",
+			Help: "Number of expired WANGuard API responses removed from the cache",
+		},
+		[]string{"api_address", "endpoint"},
+	)
+)
+
+// InitMetrics returns the API client metrics to be registered by the exporter
+func InitMetrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		wanguardAPIUp,
+		apiCircuitState,
+		apiCircuitTransitions,
+		apiRequestDuration,
+		apiRequestsTotal,
+		apiResponseSize,
+		apiResponseOversize,
+		apiParseErrors,
+		apiRequestsDeduplicated,
+		apiLimiterWait,
+		apiLimiterRejected,
+		apiCredentialsReloadSuccess,
+		apiCredentialsReloadTimestamp,
+		apiSSHTunnelUp,
+		apiSSHTunnelReconnects,
+		apiCacheHits,
+		apiCacheMisses,
+		apiCacheEvictions,
+	}
+}
@@ -0,0 +1,55 @@
+package wgc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestEndpointTemplate(t *testing.T) {
+	tests := map[string]string{
+		"/wanguard-api/v1/license_manager":                   "license_manager",
+		"/wanguard-api/v1/responses/12/actions":              "responses/{id}/actions",
+		"/wanguard-api/v1/responses/1/actions/3/status":      "responses/{id}/actions/{id}/status",
+		"/proxy/wanguard-api/v1/anomalies?status=Active":     "anomalies",
+		"sensor_live_tops?top_type=Talkers&unit=Bits":        "sensor_live_tops",
+		"/wanguard-api/v1/flow_sensors/4-1-0/interfaces/2/x": "flow_sensors/{id}/interfaces/{id}/x",
+	}
+
+	for path, expected := range tests {
+		if got := endpointTemplate(path); got != expected {
+			t.Errorf("endpointTemplate(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+}
+
+func TestRequestInstrumentation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`not json`)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false)
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	target := client.GetSanitizedTarget()
+
+	var response Response
+	if err := client.GetParsed("responses/7/actions", &response); err == nil {
+		t.Error("Expected parse error")
+	}
+
+	if got := testutil.ToFloat64(apiRequestsTotal.WithLabelValues(target, "responses/{id}/actions", "200")); got != 1 {
+		t.Errorf("Expected 1 request, got %v", got)
+	}
+
+	if got := testutil.ToFloat64(apiParseErrors.WithLabelValues(target, "responses/{id}/actions")); got != 1 {
+		t.Errorf("Expected 1 parse error, got %v", got)
+	}
+}
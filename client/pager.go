@@ -0,0 +1,190 @@
+package wgc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/tomvil/wanguard_exporter/logging"
+)
+
+// Pagination controls how list endpoints are fetched by List
+type Pagination struct {
+	// PageSize is the number of items requested per page through the limit and offset
+	// query parameters; 0 fetches the whole list with a single request
+	PageSize int
+	// MaxItems caps the number of items returned for one list; 0 means unlimited
+	MaxItems int
+}
+
+// DefaultPagination is used by clients created without WithPagination
+var DefaultPagination = Pagination{
+	PageSize: 500,
+	MaxItems: 10000,
+}
+
+// WithPagination overrides the DefaultPagination of a Client
+func WithPagination(p Pagination) Option {
+	return func(c *Client) error {
+		if p.PageSize < 0 || p.MaxItems < 0 {
+			return errors.New("pagination settings must not be negative")
+		}
+		c.pagination = p
+		return nil
+	}
+}
+
+// Pagination returns the pagination settings used by List for this client
+func (c *Client) Pagination() Pagination {
+	return c.pagination
+}
+
+// Identified is implemented by list items that carry an ID. List skips items whose ID
+// it already returned, e.g. when the list changes between two page requests.
+type Identified interface {
+	ListItemID() string
+}
+
+// Iterator walks the items of a WANGuard list endpoint page by page:
+//
+//	it := wgc.List[Anomaly](ctx, api, "anomalies?status=Active")
+//	for it.Next() {
+//		anomaly := it.Item()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator[T any] struct {
+	ctx        context.Context
+	api        API
+	path       string
+	pagination Pagination
+
+	page      []T
+	previous  []T
+	seen      map[string]struct{}
+	index     int
+	offset    int
+	count     int
+	item      T
+	last      bool
+	truncated bool
+	err       error
+}
+
+// List returns an Iterator over the list endpoint at path. The pagination settings are
+// taken from api when it provides a Pagination method, DefaultPagination is used otherwise.
+func List[T any](ctx context.Context, api API, path string) *Iterator[T] {
+	pagination := DefaultPagination
+	if p, ok := api.(interface{ Pagination() Pagination }); ok {
+		pagination = p.Pagination()
+	}
+
+	return &Iterator[T]{ctx: ctx, api: api, path: path, pagination: pagination}
+}
+
+// Next advances to the next item, fetching the next page when needed.
+// It returns false once the list is exhausted, MaxItems is reached or an error occurred.
+func (it *Iterator[T]) Next() bool {
+	if it.err != nil || it.truncated {
+		return false
+	}
+
+	if it.index == len(it.page) {
+		if it.last || !it.fetch() {
+			return false
+		}
+	}
+
+	if it.pagination.MaxItems > 0 && it.count == it.pagination.MaxItems {
+		it.truncated = true
+		logging.Warn("List %s has more than %d items, ignoring the rest (see -api.max-items)", it.path, it.pagination.MaxItems)
+		return false
+	}
+
+	it.item = it.page[it.index]
+	it.index++
+	it.count++
+	return true
+}
+
+// Item returns the current item
+func (it *Iterator[T]) Item() T {
+	return it.item
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// Truncated reports whether the iteration stopped because MaxItems was reached
+func (it *Iterator[T]) Truncated() bool {
+	return it.truncated
+}
+
+// fetch loads the next page and reports whether it contains any items
+func (it *Iterator[T]) fetch() bool {
+	size := it.pagination.PageSize
+
+	path := it.path
+	if size > 0 {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		path += sep + "limit=" + strconv.Itoa(size) + "&offset=" + strconv.Itoa(it.offset)
+	}
+
+	// Decoded while the response is read, so a page is never held twice in memory
+	var page []T
+	if err := EachElement(it.ctx, it.api, path, func(item T) error {
+		page = append(page, item)
+		return nil
+	}); err != nil {
+		it.err = err
+		return false
+	}
+
+	// A short page is the last one. So is a page larger than requested: the endpoint
+	// ignores the paging parameters and returned the whole list.
+	it.last = size == 0 || len(page) != size
+	it.offset += len(page)
+
+	if size > 0 {
+		fresh := it.unseen(page)
+		// An endpoint ignoring the paging parameters returns the same items again when the
+		// whole list fills exactly one page
+		if (len(fresh) == 0 && len(page) > 0) || reflect.DeepEqual(page, it.previous) {
+			it.last, fresh = true, nil
+		}
+		it.previous = page
+		page = fresh
+	}
+	it.page = page
+	it.index = 0
+
+	return len(page) > 0
+}
+
+// unseen returns the items of page whose ID was not returned before. Items that are not
+// Identified are all kept.
+func (it *Iterator[T]) unseen(page []T) []T {
+	fresh := make([]T, 0, len(page))
+	for _, item := range page {
+		if identified, ok := any(item).(Identified); ok && identified.ListItemID() != "" {
+			id := identified.ListItemID()
+			if _, dup := it.seen[id]; dup {
+				continue
+			}
+			if it.seen == nil {
+				it.seen = make(map[string]struct{})
+			}
+			it.seen[id] = struct{}{}
+		}
+		fresh = append(fresh, item)
+	}
+	return fresh
+}
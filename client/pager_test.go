@@ -0,0 +1,215 @@
+package wgc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// newListServer serves a list of total items honoring the limit and offset parameters
+func newListServer(t *testing.T, total int, paginated bool, requests *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+
+		start, end := 0, total
+		if paginated && r.URL.Query().Get("limit") != "" {
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			start, _ = strconv.Atoi(r.URL.Query().Get("offset"))
+			if start > total {
+				start = total
+			}
+			if end = start + limit; end > total {
+				end = total
+			}
+		}
+
+		body := "["
+		for i := start; i < end; i++ {
+			if i > start {
+				body += ","
+			}
+			body += fmt.Sprintf(`{"test": "%d"}`, i)
+		}
+		body += "]"
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func collectList(t *testing.T, it *Iterator[Response]) []string {
+	var items []string
+	for it.Next() {
+		items = append(items, it.Item().Test)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	return items
+}
+
+func TestListPaginates(t *testing.T) {
+	var requests int32
+	server := newListServer(t, 25, true, &requests)
+
+	client, err := NewClient(server.URL, "u", "p", false, WithPagination(Pagination{PageSize: 10}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	items := collectList(t, List[Response](context.Background(), client, "anomalies?status=Active"))
+	if len(items) != 25 || items[0] != "0" || items[24] != "24" {
+		t.Errorf("Expected items 0..24, got %v", items)
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("Expected 3 page requests, got %d", got)
+	}
+}
+
+func TestListMaxItems(t *testing.T) {
+	var requests int32
+	server := newListServer(t, 100, true, &requests)
+
+	client, err := NewClient(server.URL, "u", "p", false, WithPagination(Pagination{PageSize: 10, MaxItems: 15}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	it := List[Response](context.Background(), client, "anomalies")
+	if items := collectList(t, it); len(items) != 15 {
+		t.Errorf("Expected 15 items, got %d", len(items))
+	}
+	if !it.Truncated() {
+		t.Error("Expected the list to be reported as truncated")
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("Expected 2 page requests, got %d", got)
+	}
+}
+
+func TestListEndpointWithoutPaging(t *testing.T) {
+	var requests int32
+	server := newListServer(t, 25, false, &requests)
+
+	client, err := NewClient(server.URL, "u", "p", false, WithPagination(Pagination{PageSize: 10}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	if items := collectList(t, List[Response](context.Background(), client, "anomalies")); len(items) != 25 {
+		t.Errorf("Expected 25 items, got %d", len(items))
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("Expected a single request when the endpoint ignores paging, got %d", got)
+	}
+}
+
+func TestListEndpointWithoutPagingFullPage(t *testing.T) {
+	var requests int32
+	server := newListServer(t, 20, false, &requests)
+
+	client, err := NewClient(server.URL, "u", "p", false, WithPagination(Pagination{PageSize: 20, MaxItems: 100}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	// The whole list fills exactly one page, its repetition ends the iteration
+	if items := collectList(t, List[Response](context.Background(), client, "anomalies")); len(items) != 20 {
+		t.Errorf("Expected 20 items, got %d", len(items))
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("Expected 2 requests, got %d", got)
+	}
+
+	// Pages of items with IDs are compared by ID
+	it := List[identifiedResponse](context.Background(), client, "anomalies")
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if err := it.Err(); err != nil || count != 20 {
+		t.Errorf("Expected 20 items, got %d (%v)", count, err)
+	}
+}
+
+// identifiedResponse is a list item with an ID
+type identifiedResponse struct {
+	Test string `json:"test"`
+}
+
+func (r identifiedResponse) ListItemID() string {
+	return r.Test
+}
+
+func TestListSkipsRepeatedItems(t *testing.T) {
+	// Every page after the first repeats the last item of the previous one, like a list
+	// that got a new item at its head between two requests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if offset > 0 {
+			offset--
+		}
+		body := "["
+		for i := offset; i < offset+10 && i < 25; i++ {
+			if i > offset {
+				body += ","
+			}
+			body += fmt.Sprintf(`{"test": "%d"}`, i)
+		}
+		body += "]"
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false, WithPagination(Pagination{PageSize: 10}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	var items []string
+	it := List[identifiedResponse](context.Background(), client, "anomalies")
+	for it.Next() {
+		items = append(items, it.Item().Test)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	if len(items) != 25 || items[9] != "9" || items[10] != "10" || items[24] != "24" {
+		t.Errorf("Expected items 0..24 once, got %v", items)
+	}
+}
+
+func TestListError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false)
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	it := List[Response](context.Background(), client, "anomalies")
+	if it.Next() {
+		t.Error("Expected no items")
+	}
+	if it.Err() == nil {
+		t.Error("Expected an error")
+	}
+
+	if _, err := NewClient(server.URL, "u", "p", false, WithPagination(Pagination{PageSize: -1})); err == nil {
+		t.Error("Expected error for a negative page size")
+	}
+}
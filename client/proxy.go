@@ -0,0 +1,68 @@
+package wgc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/tomvil/wanguard_exporter/logging"
+)
+
+// WithProxy routes API requests through the proxy at proxyURL. Supported schemes are
+// http, https (CONNECT over TLS to the proxy) and socks5; credentials in the URL are used
+// to authenticate against the proxy. Without this option the client honors the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func WithProxy(proxyURL string) Option {
+	return func(c *Client) error {
+		if proxyURL == "" {
+			return nil
+		}
+
+		if c.sshTunnel != nil {
+			return errors.New("a proxy cannot be combined with an SSH tunnel")
+		}
+
+		u, err := parseProxyURL(proxyURL)
+		if err != nil {
+			return err
+		}
+
+		transport, ok := c.httpClient.Transport.(*http.Transport)
+		if !ok {
+			return errors.New("proxy settings require an *http.Transport")
+		}
+
+		if u.Scheme == "http" && u.User != nil {
+			logging.Warn("Proxy credentials for %s are sent in cleartext, consider an https proxy URL", u.Redacted())
+		}
+
+		transport.Proxy = http.ProxyURL(u)
+		c.proxyURL = u
+		return nil
+	}
+}
+
+// parseProxyURL validates a proxy URL given with -api.proxy-url
+func parseProxyURL(proxyURL string) (*url.URL, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https or socks5)", u.Scheme)
+	}
+
+	if u.Host == "" {
+		return nil, errors.New("proxy URL must include host")
+	}
+
+	if u.Path != "" && u.Path != "/" || u.RawQuery != "" {
+		return nil, errors.New("proxy URL must not include a path or query")
+	}
+
+	return u, nil
+}
@@ -0,0 +1,54 @@
+package wgc
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how failed idempotent GET requests are retried
+type RetryPolicy struct {
+	// MaxRetries is the number of additional attempts after the first one; 0 disables retries
+	MaxRetries int
+	// MinBackoff is the base delay before the first retry, doubled on every further attempt
+	MinBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is used by clients created without WithRetryPolicy
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 2,
+	MinBackoff: 200 * time.Millisecond,
+	MaxBackoff: 2 * time.Second,
+}
+
+// WithRetryPolicy overrides the DefaultRetryPolicy of a Client
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) error {
+		if policy.MaxRetries < 0 {
+			return errors.New("retry count must not be negative")
+		}
+		if policy.MinBackoff <= 0 || policy.MaxBackoff < policy.MinBackoff {
+			return errors.New("retry backoff must be positive and not exceed the maximum backoff")
+		}
+		c.retryPolicy = policy
+		return nil
+	}
+}
+
+// backoff returns the jittered delay before retry number attempt (starting at 0).
+// The delay grows exponentially and is picked randomly from its upper half so that
+// concurrent collectors do not retry in lockstep.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MinBackoff
+	for i := 0; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,114 @@
+package wgc
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fastRetryPolicy = RetryPolicy{
+	MaxRetries: 2,
+	MinBackoff: time.Millisecond,
+	MaxBackoff: 5 * time.Millisecond,
+}
+
+func TestGetRetriesTransientErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"test": "success"}`)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false, WithRetryPolicy(fastRetryPolicy))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	var response Response
+	if err := client.GetParsed("/test", &response); err != nil {
+		t.Errorf(errMsgExpectedNoError, err)
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("Expected 3 requests, got %d", got)
+	}
+}
+
+func TestGetDoesNotRetryClientErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false, WithRetryPolicy(fastRetryPolicy))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	_, err = client.Get("test")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *APIError, got %T: %v", err, err)
+	}
+
+	if apiErr.StatusCode != http.StatusUnauthorized || apiErr.Endpoint != "/wanguard-api/v1/test" {
+		t.Errorf("Unexpected APIError: %+v", apiErr)
+	}
+
+	if !IsUnauthorized(err) || IsRetryable(err) {
+		t.Errorf("Expected non-retryable unauthorized error, got %v", err)
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("Expected 1 request, got %d", got)
+	}
+}
+
+func TestGetNetworkErrorIsRetryable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serverURL := server.URL
+	server.Close()
+
+	client, err := NewClient(serverURL, "u", "p", false, WithRetryPolicy(fastRetryPolicy))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	_, err = client.Get("/test")
+
+	var netErr *NetworkError
+	if !errors.As(err, &netErr) || !IsRetryable(err) {
+		t.Errorf("Expected retryable *NetworkError, got %T: %v", err, err)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 5, MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for attempt := 0; attempt < 6; attempt++ {
+		delay := policy.backoff(attempt)
+		if delay < policy.MinBackoff/2 || delay > policy.MaxBackoff {
+			t.Errorf("Backoff for attempt %d out of bounds: %s", attempt, delay)
+		}
+	}
+}
+
+func TestWithRetryPolicyValidation(t *testing.T) {
+	_, err := NewClient("http://127.0.0.1", "u", "p", false, WithRetryPolicy(RetryPolicy{MaxRetries: -1, MinBackoff: time.Second, MaxBackoff: time.Second}))
+	if err == nil {
+		t.Error("Expected error for negative retry count")
+	}
+}
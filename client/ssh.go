@@ -0,0 +1,261 @@
+package wgc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tomvil/wanguard_exporter/logging"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHConfig describes an SSH server through which connections to the WANGuard API are tunneled
+type SSHConfig struct {
+	// Host is the SSH server as host[:port]; the port defaults to 22
+	Host string
+	// User is the SSH login name
+	User string
+	// KeyFile is an unencrypted private key used for public key authentication
+	KeyFile string
+	// KnownHostsFile is an OpenSSH known_hosts file used to verify the server's host key
+	KnownHostsFile string
+	// KeepAlive is the interval between keepalive requests that detect a dead tunnel; 0 uses 30s
+	KeepAlive time.Duration
+}
+
+// WithSSHTunnel makes the client open its connections to the WANGuard API through an SSH
+// connection (the equivalent of ssh -L). The API address is resolved on the SSH server, so
+// http://127.0.0.1 reaches a console listening on the SSH server's loopback interface.
+// The SSH connection is established on first use and re-established when it fails.
+func WithSSHTunnel(cfg SSHConfig) Option {
+	return func(c *Client) error {
+		if cfg.Host == "" {
+			return nil
+		}
+
+		if c.proxyURL != nil {
+			return errors.New("an SSH tunnel cannot be combined with a proxy")
+		}
+
+		transport, ok := c.httpClient.Transport.(*http.Transport)
+		if !ok {
+			return errors.New("SSH tunnel settings require an *http.Transport")
+		}
+
+		tunnel, err := newSSHTunnel(cfg, c.GetSanitizedTarget())
+		if err != nil {
+			return err
+		}
+
+		transport.Proxy = nil
+		transport.DialContext = tunnel.dialContext
+		c.sshTunnel = tunnel
+		return nil
+	}
+}
+
+// sshTunnel lazily maintains one SSH connection and dials through it
+type sshTunnel struct {
+	addr      string
+	config    *ssh.ClientConfig
+	keepAlive time.Duration
+	target    string
+
+	mu        sync.Mutex
+	client    *ssh.Client
+	connected bool
+	closed    bool
+}
+
+func newSSHTunnel(cfg SSHConfig, target string) (*sshTunnel, error) {
+	if cfg.User == "" || cfg.KeyFile == "" || cfg.KnownHostsFile == "" {
+		return nil, errors.New("an SSH tunnel requires a user, a key file and a known_hosts file")
+	}
+
+	addr := cfg.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+
+	key, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key file: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, errors.New("passphrase protected SSH keys are not supported")
+		}
+		return nil, fmt.Errorf("failed to parse SSH key file: %w", err)
+	}
+
+	hostKeyCallback, err := knownhosts.New(cfg.KnownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known_hosts file: %w", err)
+	}
+
+	keepAlive := cfg.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = 30 * time.Second
+	}
+
+	apiSSHTunnelUp.WithLabelValues(target).Set(0)
+
+	return &sshTunnel{
+		addr: addr,
+		config: &ssh.ClientConfig{
+			User:            cfg.User,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         10 * time.Second,
+		},
+		keepAlive: keepAlive,
+		target:    target,
+	}, nil
+}
+
+// dialContext opens a connection to addr from the SSH server, reconnecting once if the
+// SSH connection turns out to be broken
+func (t *sshTunnel) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := t.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := client.DialContext(ctx, network, addr)
+	var rejected *ssh.OpenChannelError
+	if err == nil || ctx.Err() != nil || errors.As(err, &rejected) {
+		// A rejected channel means the SSH server could not reach addr; the tunnel itself is fine
+		return conn, err
+	}
+
+	logging.Warn("SSH tunnel to %s failed, reconnecting: %v", t.addr, err)
+	t.reset(client)
+
+	client, err = t.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.DialContext(ctx, network, addr)
+}
+
+// connect returns the current SSH connection, establishing a new one if needed
+func (t *sshTunnel) connect(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, errors.New("SSH tunnel: closed")
+	}
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	dialer := net.Dialer{Timeout: t.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return nil, fmt.Errorf("SSH tunnel: %w", err)
+	}
+
+	// The SSH handshake does not take a context, bound it with a deadline instead
+	deadline := time.Now().Add(t.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SSH tunnel: %w", err)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, t.addr, t.config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SSH tunnel: %w", err)
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		sshConn.Close()
+		return nil, fmt.Errorf("SSH tunnel: %w", err)
+	}
+
+	t.client = ssh.NewClient(sshConn, chans, reqs)
+	if t.connected {
+		apiSSHTunnelReconnects.WithLabelValues(t.target).Inc()
+	}
+	t.connected = true
+	apiSSHTunnelUp.WithLabelValues(t.target).Set(1)
+	logging.Info("SSH tunnel to %s established", t.addr)
+
+	go t.monitor(t.client)
+
+	return t.client, nil
+}
+
+// monitor sends keepalives over client and drops it once it stops responding
+func (t *sshTunnel) monitor(client *ssh.Client) {
+	done := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(t.keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			t.reset(client)
+			return
+		case <-ticker.C:
+			replied := make(chan error, 1)
+			go func() {
+				_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+				replied <- err
+			}()
+
+			select {
+			case err := <-replied:
+				if err != nil {
+					logging.Warn("SSH tunnel to %s lost: %v", t.addr, err)
+					client.Close()
+				}
+			case <-time.After(t.keepAlive):
+				logging.Warn("SSH tunnel to %s timed out, closing it", t.addr)
+				client.Close()
+			}
+		}
+	}
+}
+
+// reset forgets client if it is still the current connection, so the next dial reconnects
+func (t *sshTunnel) reset(client *ssh.Client) {
+	t.mu.Lock()
+	if t.client == client {
+		t.client = nil
+		apiSSHTunnelUp.WithLabelValues(t.target).Set(0)
+	}
+	t.mu.Unlock()
+
+	client.Close()
+}
+
+// close shuts the SSH connection down and prevents new ones
+func (t *sshTunnel) close() {
+	t.mu.Lock()
+	client := t.client
+	t.client = nil
+	t.closed = true
+	apiSSHTunnelUp.WithLabelValues(t.target).Set(0)
+	t.mu.Unlock()
+
+	if client != nil {
+		client.Close()
+	}
+}
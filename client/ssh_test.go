@@ -0,0 +1,217 @@
+package wgc
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshTestServer is a minimal SSH server that only supports direct-tcpip forwarding
+type sshTestServer struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+	forwards int32
+
+	mu    sync.Mutex
+	conns []*ssh.ServerConn
+}
+
+func newSSHTestServer(t *testing.T, clientKey ssh.PublicKey) (*sshTestServer, ssh.PublicKey) {
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "exporter" && string(key.Marshal()) == string(clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, ssh.ErrNoAuth
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &sshTestServer{listener: listener, config: config}
+	t.Cleanup(func() {
+		listener.Close()
+		s.dropConnections()
+	})
+	go s.serve()
+
+	return s, hostSigner.PublicKey()
+}
+
+func (s *sshTestServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			sshConn, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, sshConn)
+			s.mu.Unlock()
+
+			go ssh.DiscardRequests(reqs)
+			for ch := range chans {
+				go s.forward(ch)
+			}
+		}()
+	}
+}
+
+func (s *sshTestServer) forward(ch ssh.NewChannel) {
+	if ch.ChannelType() != "direct-tcpip" {
+		_ = ch.Reject(ssh.UnknownChannelType, "unsupported")
+		return
+	}
+
+	// RFC 4254 section 7.2: host to connect, port to connect, originator address and port
+	payload := ch.ExtraData()
+	hostLen := binary.BigEndian.Uint32(payload)
+	host := string(payload[4 : 4+hostLen])
+	port := binary.BigEndian.Uint32(payload[4+hostLen:])
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		_ = ch.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, reqs, err := ch.Accept()
+	if err != nil {
+		target.Close()
+		return
+	}
+	atomic.AddInt32(&s.forwards, 1)
+	go ssh.DiscardRequests(reqs)
+
+	go func() {
+		_, _ = io.Copy(target, channel)
+		target.Close()
+	}()
+	_, _ = io.Copy(channel, target)
+	channel.Close()
+}
+
+func (s *sshTestServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func TestSSHTunnel(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"test": "success"}`)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	defer api.Close()
+
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshClientPub, err := ssh.NewPublicKey(clientPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, hostKey := newSSHTestServer(t, sshClientPub)
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "id_ed25519")
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	knownHostsFile := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{server.listener.Addr().String()}, hostKey)
+	if err := os.WriteFile(knownHostsFile, []byte(line+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(api.URL, "u", "p", false, noRetries(), WithSSHTunnel(SSHConfig{
+		Host:           server.listener.Addr().String(),
+		User:           "exporter",
+		KeyFile:        keyFile,
+		KnownHostsFile: knownHostsFile,
+	}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	target := client.GetSanitizedTarget()
+
+	if _, err := client.Get("test"); err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	if got := atomic.LoadInt32(&server.forwards); got != 1 {
+		t.Errorf("Expected 1 forwarded connection, got %d", got)
+	}
+	if got := testutil.ToFloat64(apiSSHTunnelUp.WithLabelValues(target)); got != 1 {
+		t.Errorf("Expected tunnel to be up, got %v", got)
+	}
+
+	// Kill the SSH connection and the idle HTTP connection riding on it
+	server.dropConnections()
+	client.httpClient.Transport.(*http.Transport).CloseIdleConnections()
+
+	if _, err := client.Get("test"); err != nil {
+		t.Fatalf("Expected request to succeed after reconnecting, got %v", err)
+	}
+	if got := testutil.ToFloat64(apiSSHTunnelReconnects.WithLabelValues(target)); got != 1 {
+		t.Errorf("Expected 1 reconnect, got %v", got)
+	}
+
+	client.Close()
+	if got := testutil.ToFloat64(apiSSHTunnelUp.WithLabelValues(target)); got != 0 {
+		t.Errorf("Expected tunnel to be down after Close, got %v", got)
+	}
+	if _, err := client.Get("test"); err == nil {
+		t.Error("Expected requests through a closed tunnel to fail")
+	}
+}
+
+func TestSSHTunnelValidation(t *testing.T) {
+	if _, err := NewClient("http://127.0.0.1", "u", "p", false, WithSSHTunnel(SSHConfig{Host: "jump"})); err == nil {
+		t.Error("Expected error for an SSH tunnel without user, key and known_hosts")
+	}
+
+	if _, err := NewClient("http://127.0.0.1", "u", "p", false, WithProxy("http://proxy:3128"), WithSSHTunnel(SSHConfig{Host: "jump"})); err == nil {
+		t.Error("Expected error for an SSH tunnel combined with a proxy")
+	}
+}
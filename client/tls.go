@@ -0,0 +1,126 @@
+package wgc
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// TLSConfig holds the TLS settings used to verify and authenticate against the WANGuard API
+type TLSConfig struct {
+	// CAFile is a PEM bundle of the CAs trusted instead of the system pool
+	CAFile string
+	// CertFile and KeyFile hold a PEM client certificate and key for mutual TLS
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the host name the server certificate is verified against
+	ServerName string
+	// PinnedSHA256 lists SHA-256 fingerprints of certificates, one of which must be
+	// part of the chain presented by the server (hex, colons optional)
+	PinnedSHA256 []string
+}
+
+// WithTLS applies cfg to the TLS configuration of the client's transport.
+// Certificate pinning also applies when -api.insecure disabled chain verification,
+// which allows trusting a self-signed console certificate by its fingerprint only.
+func WithTLS(cfg TLSConfig) Option {
+	return func(c *Client) error {
+		if cfg.CAFile == "" && cfg.CertFile == "" && cfg.KeyFile == "" && cfg.ServerName == "" && len(cfg.PinnedSHA256) == 0 {
+			return nil
+		}
+
+		if !strings.HasPrefix(c.apiAddress, "https://") {
+			return errors.New("TLS settings require an https API address")
+		}
+
+		transport, ok := c.httpClient.Transport.(*http.Transport)
+		if !ok {
+			return errors.New("TLS settings require an *http.Transport")
+		}
+		tlsConfig := transport.TLSClientConfig
+
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return fmt.Errorf("failed to read CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("no certificates found in CA file %s", cfg.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+
+		if cfg.CertFile != "" || cfg.KeyFile != "" {
+			if cfg.CertFile == "" || cfg.KeyFile == "" {
+				return errors.New("both client certificate and key file must be set")
+			}
+			cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to load client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		if cfg.ServerName != "" {
+			tlsConfig.ServerName = cfg.ServerName
+		}
+
+		if len(cfg.PinnedSHA256) > 0 {
+			pins, err := parseFingerprints(cfg.PinnedSHA256)
+			if err != nil {
+				return err
+			}
+			tlsConfig.VerifyConnection = verifyPins(pins)
+		}
+
+		return nil
+	}
+}
+
+// parseFingerprints decodes hex SHA-256 fingerprints such as the output of
+// "openssl x509 -noout -fingerprint -sha256"
+func parseFingerprints(fingerprints []string) ([][]byte, error) {
+	var pins [][]byte
+
+	for _, fp := range fingerprints {
+		fp = strings.TrimSpace(fp)
+		if fp == "" {
+			continue
+		}
+
+		pin, err := hex.DecodeString(strings.ReplaceAll(fp, ":", ""))
+		if err != nil || len(pin) != sha256.Size {
+			return nil, fmt.Errorf("invalid SHA-256 certificate fingerprint %q", fp)
+		}
+		pins = append(pins, pin)
+	}
+
+	if len(pins) == 0 {
+		return nil, errors.New("no certificate fingerprints given")
+	}
+
+	return pins, nil
+}
+
+// verifyPins rejects connections whose certificate chain contains none of the pinned certificates
+func verifyPins(pins [][]byte) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		for _, cert := range cs.PeerCertificates {
+			sum := sha256.Sum256(cert.Raw)
+			for _, pin := range pins {
+				if subtle.ConstantTimeCompare(sum[:], pin) == 1 {
+					return nil
+				}
+			}
+		}
+		return errors.New("server certificate does not match any pinned SHA-256 fingerprint")
+	}
+}
@@ -0,0 +1,88 @@
+package wgc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTLSTestServer(t *testing.T) *httptest.Server {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"test": "success"}`)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTLSCAFile(t *testing.T) {
+	server := newTLSTestServer(t)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	untrusted, err := NewClient(server.URL, "u", "p", false, noRetries())
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	if _, err := untrusted.Get("test"); err == nil {
+		t.Error("Expected certificate verification error without CA file")
+	}
+
+	trusted, err := NewClient(server.URL, "u", "p", false, noRetries(), WithTLS(TLSConfig{CAFile: caFile}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	if _, err := trusted.Get("test"); err != nil {
+		t.Errorf(errMsgExpectedNoError, err)
+	}
+}
+
+func TestTLSCertificatePinning(t *testing.T) {
+	server := newTLSTestServer(t)
+
+	sum := sha256.Sum256(server.Certificate().Raw)
+	fingerprint := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	pinned, err := NewClient(server.URL, "u", "p", true, noRetries(), WithTLS(TLSConfig{PinnedSHA256: []string{fingerprint}}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	if _, err := pinned.Get("test"); err != nil {
+		t.Errorf(errMsgExpectedNoError, err)
+	}
+
+	wrongPin := strings.Repeat("00:", sha256.Size-1) + "00"
+	mismatched, err := NewClient(server.URL, "u", "p", true, noRetries(), WithTLS(TLSConfig{PinnedSHA256: []string{wrongPin}}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	if _, err := mismatched.Get("test"); err == nil {
+		t.Error("Expected pinning error for unknown fingerprint")
+	}
+}
+
+func TestTLSConfigValidation(t *testing.T) {
+	if _, err := NewClient("http://127.0.0.1", "u", "p", false, WithTLS(TLSConfig{ServerName: "console"})); err == nil {
+		t.Error("Expected error for TLS settings with an http address")
+	}
+
+	if _, err := NewClient("https://127.0.0.1", "u", "p", false, WithTLS(TLSConfig{PinnedSHA256: []string{"abcd"}})); err == nil {
+		t.Error("Expected error for a malformed fingerprint")
+	}
+
+	if _, err := NewClient("https://127.0.0.1", "u", "p", false, WithTLS(TLSConfig{CertFile: "client.pem"})); err == nil {
+		t.Error("Expected error for a client certificate without key")
+	}
+}
@@ -0,0 +1,159 @@
+package wgc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tomvil/wanguard_exporter/logging"
+)
+
+// TestedVersion is the WANGuard release the exporter's endpoints and fields were verified against
+var TestedVersion = Version{8, 3, 21}
+
+const (
+	// versionRetryInterval is how often StartVersionDetection retries a failed detection
+	versionRetryInterval = time.Minute
+	// versionDetectTimeout bounds a single detection attempt
+	versionDetectTimeout = 30 * time.Second
+)
+
+// Version is a WANGuard software version such as "8.3-21" (major.minor-build)
+type Version [3]int
+
+// ParseVersion parses a WANGuard software version. Missing minor or build numbers are 0.
+func ParseVersion(s string) (Version, error) {
+	var v Version
+
+	fields := strings.Split(strings.ReplaceAll(strings.TrimSpace(s), "-", "."), ".")
+	if len(fields) > len(v) {
+		return v, fmt.Errorf("invalid WANGuard version %q", s)
+	}
+
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid WANGuard version %q", s)
+		}
+		v[i] = n
+	}
+
+	return v, nil
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is older, equal or newer than other
+func (v Version) Compare(other Version) int {
+	for i := range v {
+		switch {
+		case v[i] < other[i]:
+			return -1
+		case v[i] > other[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// IsZero reports whether v is the zero Version, used for unknown or unbounded versions
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d-%d", v[0], v[1], v[2])
+}
+
+// versionState tracks the detected version of the console
+type versionState struct {
+	mu      sync.Mutex
+	version Version
+	known   bool
+}
+
+// Version returns the detected console version and whether it is known
+func (c *Client) Version() (Version, bool) {
+	c.versionState.mu.Lock()
+	defer c.versionState.mu.Unlock()
+
+	return c.versionState.version, c.versionState.known
+}
+
+// DetectVersion reads the console software version from license_manager and
+// remembers it for the compatibility rules (see WithCompatibility)
+func (c *Client) DetectVersion(ctx context.Context) (Version, error) {
+	fullURL, err := c.resolveURL("license_manager")
+	if err != nil {
+		return Version{}, err
+	}
+
+	body, err := c.getWithRetries(ctx, fullURL)
+	if err != nil {
+		return Version{}, fmt.Errorf("failed to detect WANGuard version: %w", err)
+	}
+
+	var license struct {
+		SoftwareVersion string `json:"software_version"`
+	}
+	if err := json.Unmarshal(body, &license); err != nil {
+		return Version{}, fmt.Errorf("failed to detect WANGuard version: %w", err)
+	}
+
+	version, err := ParseVersion(license.SoftwareVersion)
+	if err != nil {
+		return Version{}, err
+	}
+
+	c.versionState.mu.Lock()
+	previous, known := c.versionState.version, c.versionState.known
+	c.versionState.version, c.versionState.known = version, true
+	c.versionState.mu.Unlock()
+
+	if !known || previous != version {
+		logging.Info("Connected to WANGuard %s", version)
+		if version[0] != TestedVersion[0] {
+			logging.Warn("WANGuard %s differs from the tested release %s, some endpoints or fields may not be supported", version, TestedVersion)
+		}
+	}
+
+	return version, nil
+}
+
+// StartVersionDetection detects the console version in the background without delaying
+// the caller. A failed detection is logged and retried once per versionRetryInterval
+// until it succeeds or the client is closed.
+func (c *Client) StartVersionDetection() {
+	// Aborts the attempt in progress when the client is closed, e.g. by a reload
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-c.closed:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+
+	go func() {
+		defer cancel()
+		for {
+			attemptCtx, cancelAttempt := context.WithTimeout(ctx, versionDetectTimeout)
+			_, err := c.DetectVersion(attemptCtx)
+			cancelAttempt()
+			if err == nil {
+				return
+			}
+			logging.Warn("%v, retrying in %s", err, versionRetryInterval)
+
+			timer := time.NewTimer(versionRetryInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
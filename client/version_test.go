@@ -0,0 +1,131 @@
+package wgc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := map[string]Version{
+		"8.3-21":  {8, 3, 21},
+		" 8.3-21": {8, 3, 21},
+		"8.3":     {8, 3, 0},
+		"9":       {9, 0, 0},
+	}
+	for input, want := range tests {
+		got, err := ParseVersion(input)
+		if err != nil {
+			t.Errorf("ParseVersion(%q): %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseVersion(%q) = %s, want %s", input, got, want)
+		}
+	}
+
+	for _, invalid := range []string{"", "v8", "8.3-21-1", "8..3", "8.-3"} {
+		if _, err := ParseVersion(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	if (Version{8, 3, 21}).Compare(Version{8, 3, 21}) != 0 {
+		t.Error("Expected equal versions to compare as 0")
+	}
+	if (Version{8, 3, 21}).Compare(Version{8, 4, 0}) != -1 {
+		t.Error("Expected 8.3-21 to be older than 8.4-0")
+	}
+	if (Version{8, 10, 0}).Compare(Version{8, 9, 99}) != 1 {
+		t.Error("Expected 8.10-0 to be newer than 8.9-99")
+	}
+}
+
+func TestDetectVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"software_version": "8.3-21", "licensed_sensors": "5"}`)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false)
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	if _, known := client.Version(); known {
+		t.Error("Expected version to be unknown before detection")
+	}
+
+	version, err := client.DetectVersion(context.Background())
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	if version != TestedVersion {
+		t.Errorf("Expected %s, got %s", TestedVersion, version)
+	}
+	if got, known := client.Version(); !known || got != version {
+		t.Errorf("Expected detected version %s to be remembered, got %s (known=%v)", version, got, known)
+	}
+}
+
+func TestStartVersionDetection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"software_version": "8.3-21"}`)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false)
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	client.StartVersionDetection()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if version, known := client.Version(); known {
+			if version != TestedVersion {
+				t.Errorf("Expected %s, got %s", TestedVersion, version)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the version to be detected in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartVersionDetectionStopsOnClose(t *testing.T) {
+	aborted := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Hangs until the detection gives up
+		<-r.Context().Done()
+		close(aborted)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false, noRetries())
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	client.StartVersionDetection()
+
+	time.Sleep(20 * time.Millisecond)
+	client.Close()
+
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Close to abort the detection in progress")
+	}
+}
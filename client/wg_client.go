@@ -1,18 +1,22 @@
 package wgc
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tomvil/wanguard_exporter/logging"
 )
 
 // Client represents the WANGuard API client
@@ -20,12 +24,44 @@ type Client struct {
 	apiAddress  string
 	apiUsername string
 	apiPassword string
+	credMu      sync.RWMutex
+	credFiles   *credentialFiles
 	httpClient  *http.Client
+	up          atomic.Bool
+	lastSuccess atomic.Int64
+	proxyURL    *url.URL
+	sshTunnel   *sshTunnel
+	retryPolicy RetryPolicy
+
+	breakerPolicy CircuitBreakerPolicy
+	breaker       *circuitBreaker
+
+	cachePolicies []CachePolicy
+	cache         *responseCache
+
+	inflight flightGroup
+
+	rateLimit RateLimit
+	limiter   *limiter
+
+	maxResponseSize int64
+
+	pagination Pagination
+
+	versionState   versionState
+	compatRules    []Compatibility
+	compatWarnings compatWarnings
+
+	closed    chan struct{}
+	closeOnce sync.Once
 }
 
+// Option configures optional behaviour of a Client
+type Option func(*Client) error
+
 // NewClient creates a new WANGuard API client with security configurations
 // If insecure is true, allows HTTP for remote hosts and skips TLS certificate verification
-func NewClient(apiAddress, apiUsername, apiPassword string, insecure bool) (*Client, error) {
+func NewClient(apiAddress, apiUsername, apiPassword string, insecure bool, opts ...Option) (*Client, error) {
 	// Validate API address
 	parsedURL, err := url.Parse(apiAddress)
 	if err != nil {
@@ -52,6 +88,7 @@ func NewClient(apiAddress, apiUsername, apiPassword string, insecure bool) (*Cli
 	httpClient := &http.Client{
 		Timeout: 30 * time.Second,
 		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
 			MaxIdleConns:          10,
 			MaxIdleConnsPerHost:   5,
 			IdleConnTimeout:       30 * time.Second,
@@ -81,12 +118,80 @@ func NewClient(apiAddress, apiUsername, apiPassword string, insecure bool) (*Cli
 		},
 	}
 
-	return &Client{
+	client := &Client{
 		apiAddress:  apiAddress,
 		apiUsername: apiUsername,
 		apiPassword: apiPassword,
 		httpClient:  httpClient,
-	}, nil
+		retryPolicy: DefaultRetryPolicy,
+
+		maxResponseSize: DefaultMaxResponseSize,
+		pagination:      DefaultPagination,
+		compatRules:     DefaultCompatibility,
+
+		breakerPolicy: DefaultCircuitBreakerPolicy,
+		rateLimit:     DefaultRateLimit,
+
+		closed: make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		if err := opt(client); err != nil {
+			return nil, err
+		}
+	}
+
+	client.breaker = newCircuitBreaker(client.breakerPolicy, client.GetSanitizedTarget())
+	client.cache = newResponseCache(client.cachePolicies, client.GetSanitizedTarget())
+	client.limiter = newLimiter(client.rateLimit, client.GetSanitizedTarget())
+
+	return client, nil
+}
+
+// DefaultMaxResponseSize is the largest response body accepted by clients created without WithMaxResponseSize
+const DefaultMaxResponseSize = 10 * 1024 * 1024 // 10MB
+
+// WithMaxResponseSize overrides the DefaultMaxResponseSize of a Client
+func WithMaxResponseSize(limit int64) Option {
+	return func(c *Client) error {
+		if limit <= 0 {
+			return errors.New("maximum response size must be positive")
+		}
+		c.maxResponseSize = limit
+		return nil
+	}
+}
+
+// Close releases the idle connections of the client and closes its SSH tunnel, if any.
+// Requests started afterwards still work unless they need the tunnel.
+func (c *Client) Close() {
+	c.closeOnce.Do(func() { close(c.closed) })
+	c.httpClient.CloseIdleConnections()
+	if c.sshTunnel != nil {
+		c.sshTunnel.close()
+	}
+}
+
+// Up reports whether the last request to the API got a successful response, like wanguard_api_up
+func (c *Client) Up() bool {
+	return c.up.Load()
+}
+
+// LastSuccess returns when the API last answered a request successfully, or the zero
+// time if it never did
+func (c *Client) LastSuccess() time.Time {
+	nanos := c.lastSuccess.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// Ping checks that the API is reachable and accepts the credentials with a request to
+// license_manager, a small single-object endpoint. The response cache is bypassed.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.GetContext(BypassCache(ctx), "license_manager")
+	return err
 }
 
 // GetSanitizedTarget extracts a safe, low-cardinality identifier from the API address
@@ -110,6 +215,172 @@ func basicAuth(username, password string) string {
 
 // Get performs an HTTP GET request to the WANGuard API
 func (c *Client) Get(path string) ([]byte, error) {
+	return c.GetContext(context.Background(), path)
+}
+
+// GetContext performs an HTTP GET request to the WANGuard API.
+// The request is aborted as soon as ctx is cancelled or its deadline expires.
+// Responses of endpoints with a cache policy are served from the cache while
+// fresh, unless ctx was created by BypassCache. Concurrent requests for the same
+// URL share a single upstream request, so the returned slice must not be modified.
+func (c *Client) GetContext(ctx context.Context, path string) ([]byte, error) {
+	fullURL, err := c.resolveURL(path)
+	if err != nil {
+		return nil, err
+	}
+
+	template := endpointTemplate(fullURL.Path)
+	rule, version := c.compatibility(template)
+	if rule != nil {
+		if fullURL, err = c.adaptRequest(rule, version, template, fullURL); err != nil {
+			return nil, err
+		}
+	}
+
+	key := fullURL.String()
+	ttl := c.cache.ttl(template)
+
+	if ttl > 0 && !cacheBypassed(ctx) {
+		if body, ok := c.cache.get(key, template); ok {
+			return body, nil
+		}
+	}
+
+	body, shared, err := c.inflight.do(ctx, key, func() ([]byte, error) {
+		body, err := c.getWithRetries(ctx, fullURL)
+		if err != nil {
+			return nil, err
+		}
+		return adaptResponse(rule, body)
+	})
+	if shared {
+		apiRequestsDeduplicated.WithLabelValues(c.GetSanitizedTarget(), template).Inc()
+	}
+	if err != nil {
+		c.warnNotFound(template, err)
+		return nil, err
+	}
+
+	if ttl > 0 && !shared {
+		c.cache.put(key, template, body, ttl)
+	}
+
+	return body, nil
+}
+
+// StreamContext fetches path like GetContext and calls decode with the response body while
+// it is read, bounded by the maximum response size like GetContext. Responses of endpoints
+// with a cache policy or with fields renamed by a compatibility rule are read whole first.
+// Streamed requests are neither cached nor coalesced, and they are not retried once decode
+// was called.
+func (c *Client) StreamContext(ctx context.Context, path string, decode func(io.Reader) error) error {
+	fullURL, err := c.resolveURL(path)
+	if err != nil {
+		return err
+	}
+
+	template := endpointTemplate(fullURL.Path)
+	rule, version := c.compatibility(template)
+	if c.cache.ttl(template) > 0 || (rule != nil && len(rule.Fields) > 0) {
+		body, err := c.GetContext(ctx, path)
+		if err != nil {
+			return err
+		}
+		return decode(bytes.NewReader(body))
+	}
+	if rule != nil {
+		if fullURL, err = c.adaptRequest(rule, version, template, fullURL); err != nil {
+			return err
+		}
+	}
+
+	err = c.request(ctx, fullURL, false, decode)
+	if err != nil {
+		c.warnNotFound(template, err)
+	}
+	return err
+}
+
+// getWithRetries sends the request to the API and returns the whole response body
+func (c *Client) getWithRetries(ctx context.Context, fullURL *url.URL) ([]byte, error) {
+	var body []byte
+	err := c.request(ctx, fullURL, true, func(r io.Reader) (err error) {
+		body, err = io.ReadAll(r)
+		return err
+	})
+	return body, err
+}
+
+// request sends the request to the API and passes the response body to read, retrying
+// failures classified as retryable (see IsRetryable) according to the client's
+// RetryPolicy. A failure while reading the body is only retried if rereadable, since
+// read may already have acted on part of it. Errors returned by read are returned as is.
+func (c *Client) request(ctx context.Context, fullURL *url.URL, rereadable bool, read func(io.Reader) error) error {
+	credentialsRefreshed := false
+
+	for attempt := 0; ; attempt++ {
+		release, err := c.limiter.acquire(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := c.breaker.allow(); err != nil {
+			release()
+			return err
+		}
+
+		started := false
+		err = c.doGet(ctx, fullURL, func(r io.Reader) error {
+			started = true
+			return read(r)
+		})
+
+		// The API answered, the body just did not decode
+		var decodeErr *decodeError
+		if errors.As(err, &decodeErr) {
+			c.breaker.record(nil, false)
+			release()
+			return decodeErr.err
+		}
+
+		c.breaker.record(err, ctx.Err() != nil)
+		release()
+
+		// Rejected credentials may have been rotated on disk: reload them and try
+		// again right away, without counting this as a retry
+		if IsUnauthorized(err) && !credentialsRefreshed {
+			credentialsRefreshed = true
+			if changed, _ := c.reloadCredentials(); changed {
+				logging.Info("Retrying %s with reloaded WANGuard API credentials", fullURL.Path)
+				attempt--
+				continue
+			}
+		}
+
+		if err == nil || attempt >= c.retryPolicy.MaxRetries || !IsRetryable(err) || (started && !rereadable) {
+			return err
+		}
+
+		delay := c.retryPolicy.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			// Retrying would outlive the caller, report the failure right away
+			return err
+		}
+
+		logging.Debug("Retrying %s in %s after error: %v", fullURL.Path, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// resolveURL turns an API path into an absolute URL on the configured API host
+func (c *Client) resolveURL(path string) (*url.URL, error) {
 	// Security: Prevent path traversal using URL resolution
 	baseURL, err := url.Parse(c.apiAddress)
 	if err != nil {
@@ -139,76 +410,138 @@ func (c *Client) Get(path string) ([]byte, error) {
 		return nil, fmt.Errorf("security violation: path attempts to override host")
 	}
 
-	req, err := http.NewRequest("GET", fullURL.String(), nil)
+	return fullURL, nil
+}
+
+// endpointTemplate normalizes an API path into a low-cardinality label value by
+// dropping the query string and the API prefix and replacing identifiers with {id},
+// e.g. "/wanguard-api/v1/responses/3/actions?x=y" becomes "responses/{id}/actions".
+func endpointTemplate(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	if i := strings.Index(path, "/wanguard-api/v1/"); i >= 0 {
+		path = path[i+len("/wanguard-api/v1/"):]
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if segment != "" && segment[0] >= '0' && segment[0] <= '9' {
+			segments[i] = "{id}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// doGet performs a single GET attempt against fullURL and passes the response body to
+// read. Errors returned by read are wrapped in a decodeError.
+func (c *Client) doGet(ctx context.Context, fullURL *url.URL, read func(io.Reader) error) error {
+	endpoint := fullURL.Path
+	target := c.GetSanitizedTarget()
+	template := endpointTemplate(endpoint)
+
+	code := "network_error"
+	start := time.Now()
+	defer func() {
+		apiRequestDuration.WithLabelValues(target, template).Observe(time.Since(start).Seconds())
+		apiRequestsTotal.WithLabelValues(target, template, code).Inc()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL.String(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.SetBasicAuth(c.apiUsername, c.apiPassword)
+	req.SetBasicAuth(c.credentials())
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		// A cancelled scrape says nothing about the API itself, so leave the metric alone
+		if ctx.Err() != nil {
+			code = "aborted"
+			return fmt.Errorf("HTTP request aborted: %w", ctx.Err())
+		}
 		// Update API up metric on error (using sanitized target)
-		wanguardAPIUp.WithLabelValues(c.GetSanitizedTarget()).Set(0)
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		wanguardAPIUp.WithLabelValues(target).Set(0)
+		c.up.Store(false)
+		return &NetworkError{Endpoint: endpoint, Err: err}
 	}
 	defer resp.Body.Close()
 
+	code = strconv.Itoa(resp.StatusCode)
+
 	// Update API up metric based on status code (using sanitized target)
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		wanguardAPIUp.WithLabelValues(c.GetSanitizedTarget()).Set(1)
+		wanguardAPIUp.WithLabelValues(target).Set(1)
+		c.up.Store(true)
+		c.lastSuccess.Store(time.Now().UnixNano())
 	} else {
-		wanguardAPIUp.WithLabelValues(c.GetSanitizedTarget()).Set(0)
+		wanguardAPIUp.WithLabelValues(target).Set(0)
+		c.up.Store(false)
 	}
 
 	// Validate status code
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+		return &APIError{Endpoint: endpoint, StatusCode: resp.StatusCode}
 	}
 
 	// Validate content type
 	contentType := resp.Header.Get("Content-Type")
 	if contentType != "" && !strings.Contains(contentType, "application/json") {
-		return nil, fmt.Errorf("expected JSON response, got %s", contentType)
+		return fmt.Errorf("expected JSON response, got %s", contentType)
 	}
 
-	// Limit response body to 10MB to prevent DoS via unbounded memory allocation
-	const maxResponseSize = 10 * 1024 * 1024 // 10MB
-	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	// Limit the response body to prevent DoS via unbounded memory allocation
+	if resp.ContentLength > c.maxResponseSize {
+		apiResponseOversize.WithLabelValues(target, template).Inc()
+		return &ResponseTooLargeError{Endpoint: endpoint, Limit: c.maxResponseSize}
 	}
 
-	return body, nil
+	body := &responseBody{r: resp.Body, limit: c.maxResponseSize}
+	readErr := read(body)
+	if readErr == nil {
+		// Drain what the decoder left, so that the connection can be reused
+		_, _ = io.Copy(io.Discard, body)
+	}
+
+	switch {
+	case body.tooLarge:
+		apiResponseOversize.WithLabelValues(target, template).Inc()
+		return &ResponseTooLargeError{Endpoint: endpoint, Limit: c.maxResponseSize}
+	case body.err != nil:
+		if ctx.Err() != nil {
+			return fmt.Errorf("HTTP request aborted: %w", ctx.Err())
+		}
+		return &NetworkError{Endpoint: endpoint, Err: fmt.Errorf("failed to read response body: %w", body.err)}
+	case readErr != nil:
+		return &decodeError{err: readErr}
+	}
+
+	apiResponseSize.WithLabelValues(target, template).Observe(float64(body.n))
+
+	return nil
 }
 
 // GetParsed performs an HTTP GET request and parses the JSON response
 func (c *Client) GetParsed(path string, obj interface{}) error {
-	body, err := c.Get(path)
+	return c.GetParsedContext(context.Background(), path, obj)
+}
+
+// GetParsedContext performs an HTTP GET request bound to ctx and parses the JSON response.
+// The response is read whole, so that it can be cached and shared by concurrent identical
+// requests; use EachElement to decode large lists while they are read.
+func (c *Client) GetParsedContext(ctx context.Context, path string, obj interface{}) error {
+	body, err := c.GetContext(ctx, path)
 	if err != nil {
 		return err
 	}
 
-	err = json.Unmarshal(body, obj)
+	err = decodeJSON(bytes.NewReader(body), obj)
 	if err != nil {
+		apiParseErrors.WithLabelValues(c.GetSanitizedTarget(), endpointTemplate(path)).Inc()
 		return fmt.Errorf("failed to parse JSON response: %w", err)
 	}
 
 	return nil
 }
-
-// Metric to track WANGuard API availability
-var (
-	wanguardAPIUp = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "wanguard_api_up",
-			Help: "Whether the WANGuard API is reachable (1 = up, 0 = down)",
-		},
-		[]string{"api_address"},
-	)
-)
-
-// InitMetrics initializes the API metrics and returns the metric
-func InitMetrics() *prometheus.GaugeVec {
-	return wanguardAPIUp
-}
@@ -1,9 +1,13 @@
 package wgc
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 const errMsgExpectedNoError = "Expected no error, got %s"
@@ -12,6 +16,11 @@ type Response struct {
 	Test string `json:"test"`
 }
 
+// noRetries makes failing requests return right away
+func noRetries() Option {
+	return WithRetryPolicy(RetryPolicy{MaxRetries: 0, MinBackoff: 1, MaxBackoff: 1})
+}
+
 func TestNewClient(t *testing.T) {
 	client, err := NewClient("http://127.0.0.1", "u", "p", false)
 	if err != nil {
@@ -110,6 +119,76 @@ func TestGetParsed(t *testing.T) {
 	}
 }
 
+func TestGetContextDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false)
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.GetContext(ctx, "/test")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded error, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected request to be aborted at the deadline, took %s", elapsed)
+	}
+}
+
+func TestPing(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/wanguard-api/v1/license_manager" {
+			t.Errorf("Unexpected ping path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(int(status.Load()))
+		if _, err := w.Write([]byte(`{"software_version": "8.3-21"}`)); err != nil {
+			t.Errorf(errMsgExpectedNoError, err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "u", "p", false, noRetries(), WithCache([]CachePolicy{{Pattern: "license_manager", TTL: time.Hour}}))
+	if err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+
+	if !client.LastSuccess().IsZero() {
+		t.Error("Expected no successful contact before the first request")
+	}
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf(errMsgExpectedNoError, err)
+	}
+	last := client.LastSuccess()
+	if time.Since(last) > time.Minute {
+		t.Errorf("Expected a recent successful contact, got %s", last)
+	}
+
+	// A cached license_manager response must not hide an unreachable API
+	status.Store(http.StatusServiceUnavailable)
+	if err := client.Ping(context.Background()); err == nil {
+		t.Error("Expected ping to fail")
+	}
+	if client.LastSuccess() != last {
+		t.Error("Expected a failed ping to leave the last successful contact unchanged")
+	}
+}
+
 func BenchmarkGetParsed(b *testing.B) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,47 @@
+package wgapi
+
+import (
+	"context"
+
+	wgc "github.com/tomvil/wanguard_exporter/client"
+)
+
+// BGPAnnouncement is an element of the bgp_announcements resource
+type BGPAnnouncement struct {
+	BGPAnnouncementID string          `json:"bgp_announcement_id"`
+	BGPConnector      BGPConnectorRef `json:"bgp_connector"`
+	Prefix            string          `json:"prefix"`
+	From              Timestamp       `json:"from"`
+	Until             Timestamp       `json:"until"`
+	Href              string          `json:"href"`
+}
+
+// ListItemID identifies the announcement across the pages of a list
+func (a BGPAnnouncement) ListItemID() string {
+	return a.BGPAnnouncementID
+}
+
+// bgpAnnouncementFields lists the fields requested for BGP announcements
+const bgpAnnouncementFields = "bgp_announcement_id,bgp_connector,prefix,from,until"
+
+// BGPAnnouncements iterates over the BGP announcements with the given status, e.g. "Active"
+func (s *Service) BGPAnnouncements(ctx context.Context, status string) *wgc.Iterator[BGPAnnouncement] {
+	return wgc.List[BGPAnnouncement](ctx, s.api, "bgp_announcements?status="+status+"&fields="+bgpAnnouncementFields)
+}
+
+// FinishedBGPAnnouncementsCount returns the number of BGP announcements with status Finished
+func (s *Service) FinishedBGPAnnouncementsCount(ctx context.Context) (Number, error) {
+	return s.count(ctx, "bgp_announcements?status=Finished&count=true")
+}
+
+// AnnouncementCounts fetches announcements?count=true
+func (s *Service) AnnouncementCounts(ctx context.Context) ([]Count, error) {
+	var counts []Count
+	err := s.api.GetParsedContext(ctx, "announcements?count=true", &counts)
+	return counts, err
+}
+
+// FinishedAnnouncementsCount fetches announcements/{id}/finished
+func (s *Service) FinishedAnnouncementsCount(ctx context.Context, id string) (Number, error) {
+	return s.count(ctx, "announcements/"+id+"/finished")
+}
@@ -0,0 +1,50 @@
+package wgapi
+
+import (
+	"context"
+
+	wgc "github.com/tomvil/wanguard_exporter/client"
+)
+
+// Anomaly is an element of the anomalies resource
+type Anomaly struct {
+	AnomalyID     string `json:"anomaly_id"`
+	Prefix        string `json:"prefix"`
+	Anomaly       string `json:"anomaly"`
+	Duration      Number `json:"duration"`
+	PktsPerSecond Number `json:"pkts/s"`
+	BitsPerSecond Number `json:"bits/s"`
+	Packets       Number `json:"packets"`
+	Bits          Number `json:"bits"`
+	Severity      Number `json:"severity"`
+	Direction     string `json:"direction"`
+	IPGroup       string `json:"ip_group"`
+	Decoder       struct {
+		DecoderID   string `json:"decoder_id"`
+		DecoderName string `json:"decoder_name"`
+	} `json:"decoder"`
+	Sensor   SensorInterfaceRef `json:"sensor"`
+	Response struct {
+		ResponseID   string `json:"response_id"`
+		ResponseName string `json:"response_name"`
+	} `json:"response"`
+	Href string `json:"href"`
+}
+
+// ListItemID identifies the anomaly across the pages of a list
+func (a Anomaly) ListItemID() string {
+	return a.AnomalyID
+}
+
+// anomalyFields lists the fields requested for active anomalies
+const anomalyFields = "anomaly_id,anomaly,prefix,duration,pkts/s,packets,bits/s,bits,severity,direction,ip_group,decoder,sensor,response"
+
+// ActiveAnomalies iterates over the anomalies with status Active
+func (s *Service) ActiveAnomalies(ctx context.Context) *wgc.Iterator[Anomaly] {
+	return wgc.List[Anomaly](ctx, s.api, "anomalies?status=Active&fields="+anomalyFields)
+}
+
+// FinishedAnomaliesCount returns the number of anomalies with status Finished
+func (s *Service) FinishedAnomaliesCount(ctx context.Context) (Number, error) {
+	return s.count(ctx, "anomalies?status=Finished&count=true")
+}
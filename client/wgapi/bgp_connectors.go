@@ -0,0 +1,34 @@
+package wgapi
+
+import "context"
+
+// BGPConnectorRef is an element of the bgp_connectors resource
+type BGPConnectorRef struct {
+	BGPConnectorID   string `json:"bgp_connector_id"`
+	BGPConnectorName string `json:"bgp_connector_name"`
+	Href             string `json:"href"`
+}
+
+// BGPConnector holds the details of a BGP connector
+type BGPConnector struct {
+	BGPConnectorID   string `json:"bgp_connector_id"`
+	BGPConnectorName string `json:"bgp_connector_name"`
+	ConnectorRole    string `json:"connector_role"`
+	DeviceGroup      string `json:"device_group"`
+	BGPFlowspec      string `json:"bgp_flowspec"`
+	Status           Ref    `json:"status"`
+}
+
+// BGPConnectors lists the BGP connectors
+func (s *Service) BGPConnectors(ctx context.Context) ([]BGPConnectorRef, error) {
+	var connectors []BGPConnectorRef
+	err := s.api.GetParsedContext(ctx, "bgp_connectors", &connectors)
+	return connectors, err
+}
+
+// BGPConnector fetches the details of the connector referenced by ref
+func (s *Service) BGPConnector(ctx context.Context, ref BGPConnectorRef) (BGPConnector, error) {
+	var connector BGPConnector
+	err := s.api.GetParsedContext(ctx, ref.Href, &connector)
+	return connector, err
+}
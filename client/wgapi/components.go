@@ -0,0 +1,66 @@
+package wgapi
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Sensor is an element of the sensors resource
+type Sensor struct {
+	SensorID   string `json:"flow_sensor_id"`
+	SensorName string `json:"sensor_name"`
+	Href       string `json:"href"`
+}
+
+// Filter is an element of the filters resource
+type Filter struct {
+	FilterID   string `json:"packet_filter_id"`
+	FilterName string `json:"filter_name"`
+	Href       string `json:"href"`
+}
+
+// Component is a sensor, filter or BGP connector as listed by Components
+type Component struct {
+	Category string
+	Name     string
+	Href     string
+}
+
+// Sensors lists the sensors
+func (s *Service) Sensors(ctx context.Context) ([]Sensor, error) {
+	var sensors []Sensor
+	err := s.api.GetParsedContext(ctx, "sensors", &sensors)
+	return sensors, err
+}
+
+// Filters lists the filters
+func (s *Service) Filters(ctx context.Context) ([]Filter, error) {
+	var filters []Filter
+	err := s.api.GetParsedContext(ctx, "filters", &filters)
+	return filters, err
+}
+
+// Components lists the components of category ("sensor", "filter" or "bgp_connector")
+// from the category's list resource, reading their names from the <category>_name field
+func (s *Service) Components(ctx context.Context, category string) ([]Component, error) {
+	var items []map[string]json.RawMessage
+	if err := s.api.GetParsedContext(ctx, category+"s", &items); err != nil {
+		return nil, err
+	}
+
+	components := make([]Component, 0, len(items))
+	for _, item := range items {
+		component := Component{Category: category}
+		// Fields that are not strings are left empty, like missing ones
+		_ = json.Unmarshal(item[category+"_name"], &component.Name)
+		_ = json.Unmarshal(item["href"], &component.Href)
+		components = append(components, component)
+	}
+
+	return components, nil
+}
+
+// ComponentStatus fetches the status of component
+func (s *Service) ComponentStatus(ctx context.Context, component Component) (Status, error) {
+	return s.Status(ctx, component.Href+"/status")
+}
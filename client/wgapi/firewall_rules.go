@@ -0,0 +1,43 @@
+package wgapi
+
+import (
+	"context"
+
+	wgc "github.com/tomvil/wanguard_exporter/client"
+)
+
+// FirewallRule is an element of the firewall_rules resource
+type FirewallRule struct {
+	FirewallRuleID    string    `json:"firewall_rule_id"`
+	AttackID          string    `json:"attack_id"`
+	SourcePrefix      string    `json:"source_prefix"`
+	DestinationPrefix string    `json:"destination_prefix"`
+	IPProtocol        string    `json:"ip_protocol"`
+	From              Timestamp `json:"from"`
+	Until             Timestamp `json:"until"`
+	PktsPerSecond     Number    `json:"pkts/s"`
+	BitsPerSecond     Number    `json:"bits/s"`
+	MaxPktsPerSecond  Number    `json:"max_pkts/s"`
+	MaxBitsPerSecond  Number    `json:"max_bits/s"`
+	Pkts              Number    `json:"pkts"`
+	Bits              Number    `json:"bits"`
+	Href              string    `json:"href"`
+}
+
+// ListItemID identifies the firewall rule across the pages of a list
+func (r FirewallRule) ListItemID() string {
+	return r.FirewallRuleID
+}
+
+// firewallRuleFields lists the fields requested for firewall rules
+const firewallRuleFields = "firewall_rule_id,attack_id,source_prefix,destination_prefix,ip_protocol,from,until,pkts/s,bits/s,max_pkts/s,max_bits/s,pkts,bits"
+
+// FirewallRules iterates over the firewall rules
+func (s *Service) FirewallRules(ctx context.Context) *wgc.Iterator[FirewallRule] {
+	return wgc.List[FirewallRule](ctx, s.api, "firewall_rules?fields="+firewallRuleFields)
+}
+
+// FirewallRulesCount returns the number of firewall rules
+func (s *Service) FirewallRulesCount(ctx context.Context) (Number, error) {
+	return s.count(ctx, "firewall_rules?count=true")
+}
@@ -0,0 +1,29 @@
+package wgapi
+
+import "context"
+
+// License is the license_manager resource
+type License struct {
+	SoftwareVersion              string `json:"software_version"`
+	LicensedSensors              Number `json:"licensed_sensors"`
+	LicensedSensorsUsed          Number `json:"licensed_sensors_used"`
+	LicensedSensorsRemaining     Number `json:"licensed_sensors_remaining"`
+	LicensedSensorInterfaces     Number `json:"licensed_sensor_interfaces"`
+	LicensedDpdkEngines          Number `json:"licensed_dpdk_engines"`
+	LicensedDpdkEnginesUsed      Number `json:"licensed_dpdk_engines_used"`
+	LicensedDpdkEnginesRemaining Number `json:"licensed_dpdk_engines_remaining"`
+	LicensedFilters              Number `json:"licensed_filters"`
+	LicensedFiltersUsed          Number `json:"licensed_filters_used"`
+	LicensedFiltersRemaining     Number `json:"licensed_filters_remaining"`
+	LicensedOn                   string `json:"licensed_on"`
+	// LicenseDaysRemaining and SupportDaysRemaining are sent as "N days"
+	LicenseDaysRemaining Number `json:"license_expiry_date_remaining"`
+	SupportDaysRemaining Number `json:"support_expiry_date_remaining"`
+}
+
+// License fetches the license_manager resource
+func (s *Service) License(ctx context.Context) (License, error) {
+	var license License
+	err := s.api.GetParsedContext(ctx, "license_manager", &license)
+	return license, err
+}
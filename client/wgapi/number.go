@@ -0,0 +1,82 @@
+package wgapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Number is a numeric value as returned by the WANGuard API, which encodes numbers
+// either as JSON numbers or as strings. Strings may carry a " days" suffix
+// (e.g. "30 days") or be "∞" for unlimited license items, which is reported as 9999.
+// Values that cannot be parsed decode without error as an invalid Number worth 0,
+// so a single odd field does not fail the whole response.
+type Number struct {
+	raw   string
+	value float64
+	valid bool
+}
+
+// unlimited is the value reported for "∞"
+const unlimited = 9999
+
+// NewNumber returns a valid Number holding v
+func NewNumber(v float64) Number {
+	return Number{raw: strconv.FormatFloat(v, 'f', -1, 64), value: v, valid: true}
+}
+
+// ParseNumber parses s using the same rules as JSON string values
+func ParseNumber(s string) Number {
+	n := Number{raw: s}
+
+	r := strings.NewReplacer(" days", "", "∞", strconv.Itoa(unlimited))
+	if v, err := strconv.ParseFloat(strings.TrimSpace(r.Replace(s)), 64); err == nil {
+		n.value, n.valid = v, true
+	}
+
+	return n
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (n *Number) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*n = Number{}
+	case len(data) > 0 && data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*n = ParseNumber(s)
+	default:
+		*n = ParseNumber(string(data))
+	}
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the value as a JSON string as sent by the API
+func (n Number) MarshalJSON() ([]byte, error) {
+	if n.raw == "" && !n.valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.raw)
+}
+
+// Float64 returns the numeric value, 0 if the API value could not be parsed
+func (n Number) Float64() float64 {
+	return n.value
+}
+
+// Valid reports whether the API value was present and numeric
+func (n Number) Valid() bool {
+	return n.valid
+}
+
+// String returns the value exactly as sent by the API
+func (n Number) String() string {
+	return n.raw
+}
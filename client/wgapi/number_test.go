@@ -0,0 +1,54 @@
+package wgapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNumberUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json  string
+		value float64
+		valid bool
+		raw   string
+	}{
+		{`12`, 12, true, "12"},
+		{`0.5`, 0.5, true, "0.5"},
+		{`"17500"`, 17500, true, "17500"},
+		{`"169.4"`, 169.4, true, "169.4"},
+		{`"30 days"`, 30, true, "30 days"},
+		{`"∞"`, unlimited, true, "∞"},
+		{`null`, 0, false, ""},
+		{`""`, 0, false, ""},
+		{`"n/a"`, 0, false, "n/a"},
+	}
+
+	for _, tt := range tests {
+		var n Number
+		if err := json.Unmarshal([]byte(tt.json), &n); err != nil {
+			t.Errorf("%s: unexpected error %v", tt.json, err)
+			continue
+		}
+		if n.Float64() != tt.value || n.Valid() != tt.valid || n.String() != tt.raw {
+			t.Errorf("%s: got value=%v valid=%v raw=%q", tt.json, n.Float64(), n.Valid(), n.String())
+		}
+	}
+}
+
+func TestNumberInStruct(t *testing.T) {
+	var v struct {
+		A Number `json:"a"`
+		B Number `json:"b"`
+	}
+	if err := json.Unmarshal([]byte(`{"a": "bogus", "b": "2 days"}`), &v); err != nil {
+		t.Fatalf("Expected invalid numbers not to fail decoding, got %v", err)
+	}
+	if v.A.Valid() || v.B.Float64() != 2 {
+		t.Errorf("Unexpected result %+v", v)
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil || string(out) != `{"a":"bogus","b":"2 days"}` {
+		t.Errorf("Expected values to round-trip, got %s (%v)", out, err)
+	}
+}
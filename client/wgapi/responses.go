@@ -0,0 +1,39 @@
+package wgapi
+
+import "context"
+
+// Response is an element of the responses resource
+type Response struct {
+	ResponseID   string `json:"response_id"`
+	ResponseName string `json:"response_name"`
+	Href         string `json:"href"`
+}
+
+// Action is an element of the responses/{id}/actions resource
+type Action struct {
+	ActionID       string `json:"action_id"`
+	ActionName     string `json:"action_name"`
+	ActionType     string `json:"action_type"`
+	ResponseBranch string `json:"response_branch"`
+	Status         Ref    `json:"status"`
+	Href           string `json:"href"`
+}
+
+// Responses lists the responses
+func (s *Service) Responses(ctx context.Context) ([]Response, error) {
+	var responses []Response
+	err := s.api.GetParsedContext(ctx, "responses", &responses)
+	return responses, err
+}
+
+// Actions lists the actions of response
+func (s *Service) Actions(ctx context.Context, response Response) ([]Action, error) {
+	var actions []Action
+	err := s.api.GetParsedContext(ctx, response.Href+"/actions", &actions)
+	return actions, err
+}
+
+// ActionStatus fetches the status of action
+func (s *Service) ActionStatus(ctx context.Context, action Action) (Status, error) {
+	return s.Status(ctx, action.Href+"/status")
+}
@@ -0,0 +1,37 @@
+package wgapi
+
+import "context"
+
+// SensorInterfaceRef identifies a sensor interface
+type SensorInterfaceRef struct {
+	InterfaceName string `json:"sensor_interface_name"`
+	InterfaceID   string `json:"sensor_interface_id"`
+	Href          string `json:"href"`
+}
+
+// SensorLiveStats is an element of the sensor_live_stats resource
+type SensorLiveStats struct {
+	Status              string             `json:"status"`
+	Sensor              SensorInterfaceRef `json:"sensor"`
+	InternalIPs         Number             `json:"internal_ips"`
+	ExternalIPs         Number             `json:"external_ips"`
+	PacketsPerSecondIn  Number             `json:"packets/s_in"`
+	PacketsPerSecondOut Number             `json:"packets/s_out"`
+	BitsPerSecondIn     Number             `json:"bits/s_in"`
+	BitsPerSecondOut    Number             `json:"bits/s_out"`
+	DroppedIn           Number             `json:"dropped_in"`
+	DroppedOut          Number             `json:"dropped_out"`
+	UsageIn             Number             `json:"usage_in"`
+	UsageOut            Number             `json:"usage_out"`
+	Load                Number             `json:"load"`
+	CPU                 Number             `json:"cpu%"`
+	RAM                 Number             `json:"ram"`
+	StartTime           Timestamp          `json:"start_time"`
+}
+
+// SensorLiveStats fetches the live statistics of all sensor interfaces
+func (s *Service) SensorLiveStats(ctx context.Context) ([]SensorLiveStats, error) {
+	var stats []SensorLiveStats
+	err := s.api.GetParsedContext(ctx, "sensor_live_stats", &stats)
+	return stats, err
+}
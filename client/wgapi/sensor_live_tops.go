@@ -0,0 +1,91 @@
+package wgapi
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// Unit selects the unit of sensor_live_tops values
+type Unit string
+
+// Direction selects the traffic direction of sensor_live_tops values
+type Direction string
+
+const (
+	Packets Unit = "Packets"
+	Bits    Unit = "Bits"
+
+	Inbound  Direction = "Inbound"
+	Outbound Direction = "Outbound"
+)
+
+// CountryTop is an entry of the Countries top
+type CountryTop struct {
+	Country string `json:"country"`
+	Value   Number `json:"value"`
+	Percent Number `json:"percent"`
+}
+
+// IPVersionTop is an entry of the IP Versions top
+type IPVersionTop struct {
+	IPVersion   Number `json:"ip_version"`
+	Description string `json:"description"`
+	Value       Number `json:"value"`
+	Percent     Number `json:"percent"`
+}
+
+// IPProtocolTop is an entry of the IP Protocols top
+type IPProtocolTop struct {
+	IPProtocol  int    `json:"ip_protocol"`
+	Description string `json:"description"`
+	Value       Number `json:"value"`
+	Percent     Number `json:"percent"`
+}
+
+// TalkerTop is an entry of the Talkers top
+type TalkerTop struct {
+	IPAddress string `json:"ip_address"`
+	Value     Number `json:"value"`
+	Percent   Number `json:"percent"`
+}
+
+// TopCountries fetches the Countries top
+func (s *Service) TopCountries(ctx context.Context, unit Unit, direction Direction) ([]CountryTop, error) {
+	return liveTop[CountryTop](ctx, s, "Countries", unit, direction)
+}
+
+// TopIPVersions fetches the IP Versions top
+func (s *Service) TopIPVersions(ctx context.Context, unit Unit, direction Direction) ([]IPVersionTop, error) {
+	return liveTop[IPVersionTop](ctx, s, "IP Versions", unit, direction)
+}
+
+// TopIPProtocols fetches the IP Protocols top
+func (s *Service) TopIPProtocols(ctx context.Context, unit Unit, direction Direction) ([]IPProtocolTop, error) {
+	return liveTop[IPProtocolTop](ctx, s, "IP Protocols", unit, direction)
+}
+
+// TopTalkers fetches the Talkers top
+func (s *Service) TopTalkers(ctx context.Context, unit Unit, direction Direction) ([]TalkerTop, error) {
+	return liveTop[TalkerTop](ctx, s, "Talkers", unit, direction)
+}
+
+// liveTop fetches a sensor_live_tops top, which the API returns as an object keyed
+// by rank ("1", "2", ...), and returns its entries ordered by rank
+func liveTop[T any](ctx context.Context, s *Service, topType string, unit Unit, direction Direction) ([]T, error) {
+	var top struct {
+		Top map[string]T `json:"top"`
+	}
+
+	path := "sensor_live_tops?top_type=" + url.PathEscape(topType) + "&unit=" + string(unit) + "&direction=" + string(direction)
+	if err := s.api.GetParsedContext(ctx, path, &top); err != nil {
+		return nil, err
+	}
+
+	entries := make([]T, 0, len(top.Top))
+	for i := 1; i <= len(top.Top); i++ {
+		entries = append(entries, top.Top[strconv.Itoa(i)])
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,71 @@
+// Package wgapi provides typed access to the resources of the WANGuard REST API
+// on top of a wgc.API, e.g. a *wgc.Client:
+//
+//	client, err := wgc.NewClient(address, username, password, false)
+//	...
+//	license, err := wgapi.New(client).License(ctx)
+//
+// Numeric fields use Number, which takes care of the API's habit of encoding
+// numbers as strings, "N days" and "∞".
+package wgapi
+
+import (
+	"context"
+
+	wgc "github.com/tomvil/wanguard_exporter/client"
+)
+
+// Service wraps a wgc.API with typed methods for each WANGuard API resource
+type Service struct {
+	api wgc.API
+}
+
+// New returns a Service sending its requests through api
+func New(api wgc.API) *Service {
+	return &Service{api: api}
+}
+
+// API returns the underlying wgc.API
+func (s *Service) API() wgc.API {
+	return s.api
+}
+
+// Ref is a reference to another API resource
+type Ref struct {
+	Href string `json:"href"`
+}
+
+// Timestamp is a point in time as returned by the API; both fields are empty for unset times
+type Timestamp struct {
+	ISO8601  string `json:"iso_8601"`
+	Unixtime Number `json:"unixtime"`
+}
+
+// Count is the response of list endpoints queried with count=true
+type Count struct {
+	Count Number `json:"count"`
+}
+
+// Status is the status of a component, response action or BGP connector
+type Status struct {
+	Status string `json:"status"`
+}
+
+// Active reports whether the status is "Active"
+func (s Status) Active() bool {
+	return s.Status == "Active"
+}
+
+// Status fetches the status resource at href, e.g. an action's href + "/status"
+func (s *Service) Status(ctx context.Context, href string) (Status, error) {
+	var status Status
+	err := s.api.GetParsedContext(ctx, href, &status)
+	return status, err
+}
+
+// count fetches a count=true query
+func (s *Service) count(ctx context.Context, path string) (Number, error) {
+	var count Count
+	err := s.api.GetParsedContext(ctx, path, &count)
+	return count.Count, err
+}
@@ -0,0 +1,91 @@
+package wgapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tomvil/wanguard_exporter/client/wgctest"
+)
+
+func TestLicense(t *testing.T) {
+	api := New(wgctest.API{"license_manager": `{
+		"software_version": "8.3-21",
+		"licensed_sensors": 2,
+		"licensed_sensor_interfaces": "∞",
+		"license_expiry_date_remaining": "30 days"
+	}`})
+
+	license, err := api.License(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if license.SoftwareVersion != "8.3-21" || license.LicensedSensors.Float64() != 2 ||
+		license.LicensedSensorInterfaces.Float64() != unlimited || license.LicenseDaysRemaining.Float64() != 30 {
+		t.Errorf("Unexpected license %+v", license)
+	}
+	if license.SupportDaysRemaining.Valid() {
+		t.Error("Expected missing field to be invalid")
+	}
+}
+
+func TestActiveAnomalies(t *testing.T) {
+	api := New(wgctest.API{"anomalies?status=Active&fields=" + anomalyFields: `[
+		{"anomaly_id": "1", "pkts/s": "17500", "sensor": {"sensor_interface_name": "br-se1-bl0"}},
+		{"anomaly_id": "2", "pkts/s": "10"}
+	]`})
+
+	it := api.ActiveAnomalies(context.Background())
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Item().AnomalyID)
+	}
+	if it.Err() != nil || len(ids) != 2 {
+		t.Fatalf("Expected 2 anomalies, got %v (%v)", ids, it.Err())
+	}
+}
+
+func TestComponents(t *testing.T) {
+	api := New(wgctest.API{"filters": `[
+		{"packet_filter_id": "1", "filter_name": "Packet Filter 1", "href": "/wanguard-api/v1/packet_filters/1"},
+		{"filter_name": {"unexpected": "object"}, "href": "/wanguard-api/v1/packet_filters/2"}
+	]`})
+
+	components, err := api.Components(context.Background(), "filter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(components) != 2 || components[0].Name != "Packet Filter 1" || components[0].Href != "/wanguard-api/v1/packet_filters/1" {
+		t.Errorf("Unexpected components %+v", components)
+	}
+	if components[1].Name != "" {
+		t.Errorf("Expected non-string name to be empty, got %q", components[1].Name)
+	}
+}
+
+func TestTopCountriesOrderedByRank(t *testing.T) {
+	api := New(wgctest.API{"sensor_live_tops?top_type=Countries&unit=Bits&direction=Inbound": `{"top": {
+		"2": {"country": "Germany", "value": 100},
+		"1": {"country": "United States", "value": "200"}
+	}}`})
+
+	top, err := api.TopCountries(context.Background(), Bits, Inbound)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 2 || top[0].Country != "United States" || top[0].Value.Float64() != 200 || top[1].Country != "Germany" {
+		t.Errorf("Unexpected top %+v", top)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	api := New(wgctest.API{"/wanguard-api/v1/responses/1/actions/1/status": `{"status": "Active"}`})
+
+	status, err := api.ActionStatus(context.Background(), Action{Href: "/wanguard-api/v1/responses/1/actions/1"})
+	if err != nil || !status.Active() {
+		t.Errorf("Expected active status, got %+v (%v)", status, err)
+	}
+
+	if _, err := api.Status(context.Background(), "missing/status"); err == nil {
+		t.Error("Expected error for unknown status resource")
+	}
+}
@@ -0,0 +1,46 @@
+// Package wgctest provides an in-memory WANGuard API for tests of code built on the
+// client package
+package wgctest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	wgc "github.com/tomvil/wanguard_exporter/client"
+)
+
+// API is an in-memory wgc.API serving canned JSON payloads by path. Paths without a
+// payload fail with a 404 APIError.
+type API map[string]string
+
+var _ wgc.API = API{}
+
+func (a API) GetContext(ctx context.Context, path string) ([]byte, error) {
+	payload, ok := a[path]
+	if !ok {
+		return nil, &wgc.APIError{Endpoint: path, StatusCode: 404}
+	}
+	return []byte(payload), nil
+}
+
+func (a API) GetParsed(path string, obj interface{}) error {
+	return a.GetParsedContext(context.Background(), path, obj)
+}
+
+func (a API) GetParsedContext(ctx context.Context, path string, obj interface{}) error {
+	payload, err := a.GetContext(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(payload, obj); err != nil {
+		return fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	return nil
+}
+
+// Pagination disables paging, so that lists are fetched with a single request whose path
+// matches the canned payload
+func (a API) Pagination() wgc.Pagination {
+	return wgc.Pagination{}
+}
@@ -1,33 +1,23 @@
 package collectors
 
 import (
-	"github.com/tomvil/wanguard_exporter/logging"
+	"context"
+	"errors"
 
 	"github.com/prometheus/client_golang/prometheus"
 	wgc "github.com/tomvil/wanguard_exporter/client"
+	"github.com/tomvil/wanguard_exporter/client/wgapi"
 )
 
 type ActionsCollector struct {
-	wgClient     *wgc.Client
+	api          *wgapi.Service
 	ActionStatus *prometheus.Desc
 }
 
-type Response struct {
-	ResponseName string `json:"response_name"`
-	ResponseHref string `json:"href"`
-}
-
-type Action struct {
-	ActionName     string `json:"action_name"`
-	ActionType     string `json:"action_type"`
-	ResponseBranch string `json:"response_branch"`
-	ActionHref     string `json:"href"`
-}
-
-func NewActionsCollector(wgclient *wgc.Client) *ActionsCollector {
+func NewActionsCollector(wgclient wgc.API) *ActionsCollector {
 	prefix := "wanguard_action"
 	return &ActionsCollector{
-		wgClient:     wgclient,
+		api:          wgapi.New(wgclient),
 		ActionStatus: prometheus.NewDesc(prefix+"status", "Status of the response actions", []string{"response_name", "action_name", "action_type", "response_branch"}, nil),
 	}
 }
@@ -37,30 +27,31 @@ func (c *ActionsCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (c *ActionsCollector) Collect(ch chan<- prometheus.Metric) {
-	var responses []Response
+	collect(c, ch)
+}
 
-	err := c.wgClient.GetParsed("responses", &responses)
+func (c *ActionsCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	responses, err := c.api.Responses(ctx)
 	if err != nil {
-		return
+		return err
 	}
-	for _, response := range responses {
-		var actions []Action
 
-		err := c.wgClient.GetParsed(response.ResponseHref+"/actions", &actions)
+	var errs []error
+	for _, response := range responses {
+		actions, err := c.api.Actions(ctx, response)
 		if err != nil {
+			errs = append(errs, err)
 			continue
 		}
 
 		for _, action := range actions {
-			var params map[string]string
-
-			err := c.wgClient.GetParsed(action.ActionHref+"/status", &params)
+			status, err := c.api.ActionStatus(ctx, action)
 			if err != nil {
-				logging.Error("Error: %v", err)
+				errs = append(errs, err)
 				continue
 			}
 
-			if params["status"] == "Active" {
+			if status.Active() {
 				ch <- prometheus.MustNewConstMetric(c.ActionStatus, prometheus.GaugeValue, 1, response.ResponseName, action.ActionName, action.ActionType, action.ResponseBranch)
 			} else {
 				ch <- prometheus.MustNewConstMetric(c.ActionStatus, prometheus.GaugeValue, 0, response.ResponseName, action.ActionName, action.ActionType, action.ResponseBranch)
@@ -68,4 +59,6 @@ func (c *ActionsCollector) Collect(ch chan<- prometheus.Metric) {
 
 		}
 	}
+
+	return errors.Join(errs...)
 }
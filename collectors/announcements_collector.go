@@ -1,29 +1,25 @@
 package collectors
 
 import (
-	"github.com/tomvil/wanguard_exporter/logging"
-
-	"strconv"
+	"context"
+	"errors"
 
 	"github.com/prometheus/client_golang/prometheus"
 	wgc "github.com/tomvil/wanguard_exporter/client"
+	"github.com/tomvil/wanguard_exporter/client/wgapi"
 )
 
 type AnnouncementsCollector struct {
-	wgClient              *wgc.Client
+	api                   *wgapi.Service
 	AnnouncementActive    *prometheus.Desc
 	AnnouncementsFinished *prometheus.Desc
 }
 
-type AnnouncementCount struct {
-	Count string
-}
-
-func NewAnnouncementsCollector(wgclient *wgc.Client) *AnnouncementsCollector {
+func NewAnnouncementsCollector(wgclient wgc.API) *AnnouncementsCollector {
 	prefix := "wanguard_announcement"
 
 	return &AnnouncementsCollector{
-		wgClient:              wgclient,
+		api:                   wgapi.New(wgclient),
 		AnnouncementActive:    prometheus.NewDesc(prefix+"active", "Active announcements", []string{"announcement_name"}, nil),
 		AnnouncementsFinished: prometheus.NewDesc(prefix+"finished", "Finished announcements", []string{"announcement_name"}, nil),
 	}
@@ -35,37 +31,40 @@ func (c *AnnouncementsCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (c *AnnouncementsCollector) Collect(ch chan<- prometheus.Metric) {
-	var announcements []AnnouncementCount
+	collect(c, ch)
+}
 
-	err := c.wgClient.GetParsed("announcements?count=true", &announcements)
+func (c *AnnouncementsCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	announcements, err := c.api.AnnouncementCounts(ctx)
 	if err != nil {
-		logging.Error("Error: %v", err)
-		return
+		return err
 	}
 
+	var errs []error
 	for _, announcement := range announcements {
-		var finishedAnnouncement AnnouncementCount
+		name := announcement.Count.String()
 
-		err := c.wgClient.GetParsed("announcements/"+announcement.Count+"/finished", &finishedAnnouncement)
+		finishedCount, err := c.api.FinishedAnnouncementsCount(ctx, name)
 		if err != nil {
+			errs = append(errs, err)
 			continue
 		}
 
-		activeCount, err := strconv.ParseFloat(announcement.Count, 64)
-		if err != nil {
-			logging.Error("Error: %v", err)
-			ch <- prometheus.MustNewConstMetric(c.AnnouncementActive, prometheus.GaugeValue, 0, announcement.Count)
+		if !announcement.Count.Valid() {
+			errs = append(errs, &invalidValueError{what: "announcement count", value: name})
+			ch <- prometheus.MustNewConstMetric(c.AnnouncementActive, prometheus.GaugeValue, 0, name)
 			continue
 		}
 
-		finishedCount, err := strconv.ParseFloat(finishedAnnouncement.Count, 64)
-		if err != nil {
-			logging.Error("Error: %v", err)
-			ch <- prometheus.MustNewConstMetric(c.AnnouncementsFinished, prometheus.GaugeValue, 0, announcement.Count)
+		if !finishedCount.Valid() {
+			errs = append(errs, &invalidValueError{what: "finished announcement count", value: finishedCount.String()})
+			ch <- prometheus.MustNewConstMetric(c.AnnouncementsFinished, prometheus.GaugeValue, 0, name)
 			continue
 		}
 
-		ch <- prometheus.MustNewConstMetric(c.AnnouncementActive, prometheus.GaugeValue, activeCount, announcement.Count)
-		ch <- prometheus.MustNewConstMetric(c.AnnouncementsFinished, prometheus.GaugeValue, finishedCount, announcement.Count)
+		ch <- prometheus.MustNewConstMetric(c.AnnouncementActive, prometheus.GaugeValue, announcement.Count.Float64(), name)
+		ch <- prometheus.MustNewConstMetric(c.AnnouncementsFinished, prometheus.GaugeValue, finishedCount.Float64(), name)
 	}
+
+	return errors.Join(errs...)
 }
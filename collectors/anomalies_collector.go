@@ -1,51 +1,24 @@
 package collectors
 
 import (
-	"github.com/tomvil/wanguard_exporter/logging"
-
-	"strconv"
+	"context"
+	"errors"
 
 	"github.com/prometheus/client_golang/prometheus"
 	wgc "github.com/tomvil/wanguard_exporter/client"
+	"github.com/tomvil/wanguard_exporter/client/wgapi"
 )
 
 type AnomaliesCollector struct {
-	wgClient          *wgc.Client
+	api               *wgapi.Service
 	AnomalyActive     *prometheus.Desc
 	AnomaliesFinished *prometheus.Desc
 }
 
-type AnomaliesCount struct {
-	Count string
-}
-
-type Anomaly struct {
-	AnomalyId string `json:"anomaly_id"`
-	Prefix    string
-	Anomaly   string
-	Duration  string
-	Pkts_s    string `json:"pkts/s"`
-	Bits_s    string `json:"bits/s"`
-	Packets   string
-	Bits      string
-	Severity  string
-	Direction string
-	IpGroup   string `json:"ip_group"`
-	Decoder   struct {
-		DecoderName string `json:"decoder_name"`
-	} `json:"decoder"`
-	Sensor struct {
-		SensorInterfaceName string `json:"sensor_interface_name"`
-	} `json:"sensor"`
-	Response struct {
-		ResponseName string `json:"response_name"`
-	} `json:"response"`
-}
-
-func NewAnomaliesCollector(wgclient *wgc.Client) *AnomaliesCollector {
+func NewAnomaliesCollector(wgclient wgc.API) *AnomaliesCollector {
 	prefix := "wanguard_anomalies"
 	return &AnomaliesCollector{
-		wgClient:          wgclient,
+		api:               wgapi.New(wgclient),
 		AnomalyActive:     prometheus.NewDesc(prefix+"active", "Active anomalies at the moment", []string{"prefix", "anomaly", "anomaly_id", "duration", "pkts_s", "packets", "bits_s", "bits", "severity", "direction", "ip_group", "decoder", "sensor", "response"}, nil),
 		AnomaliesFinished: prometheus.NewDesc(prefix+"finished", "Number of finished anomalies", nil, nil),
 	}
@@ -57,53 +30,53 @@ func (c *AnomaliesCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (c *AnomaliesCollector) Collect(ch chan<- prometheus.Metric) {
-	collectActiveAnomalies(c.AnomalyActive, c.wgClient, ch)
-	collectFinishedAnomaliesTotal(c.AnomaliesFinished, c.wgClient, ch)
+	collect(c, ch)
 }
 
-func collectActiveAnomalies(desc *prometheus.Desc, wgclient *wgc.Client, ch chan<- prometheus.Metric) {
-	var anomalies []Anomaly
-
-	err := wgclient.GetParsed("anomalies?status=Active&fields=anomaly_id,anomaly,prefix,duration,pkts/s,packets,bits/s,bits,severity,direction,ip_group,decoder,sensor,response", &anomalies)
-	if err != nil {
-		return
-	}
+func (c *AnomaliesCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	return errors.Join(
+		collectActiveAnomalies(ctx, c.AnomalyActive, c.api, ch),
+		collectFinishedAnomaliesTotal(ctx, c.AnomaliesFinished, c.api, ch),
+	)
+}
 
-	for _, anomaly := range anomalies {
+func collectActiveAnomalies(ctx context.Context, desc *prometheus.Desc, api *wgapi.Service, ch chan<- prometheus.Metric) error {
+	// The list of active anomalies can get large during an attack, fetch it page by page
+	anomalies := api.ActiveAnomalies(ctx)
+	for anomalies.Next() {
+		anomaly := anomalies.Item()
 		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1,
 			anomaly.Prefix,
 			anomaly.Anomaly,
-			anomaly.AnomalyId,
-			anomaly.Duration,
-			anomaly.Pkts_s,
-			anomaly.Packets,
-			anomaly.Bits_s,
-			anomaly.Bits,
-			anomaly.Severity,
+			anomaly.AnomalyID,
+			anomaly.Duration.String(),
+			anomaly.PktsPerSecond.String(),
+			anomaly.Packets.String(),
+			anomaly.BitsPerSecond.String(),
+			anomaly.Bits.String(),
+			anomaly.Severity.String(),
 			anomaly.Direction,
-			anomaly.IpGroup,
+			anomaly.IPGroup,
 			anomaly.Decoder.DecoderName,
-			anomaly.Sensor.SensorInterfaceName,
+			anomaly.Sensor.InterfaceName,
 			anomaly.Response.ResponseName)
 	}
-}
 
-func collectFinishedAnomaliesTotal(desc *prometheus.Desc, wgclient *wgc.Client, ch chan<- prometheus.Metric) {
-	var finishedAnomaliesCount AnomaliesCount
+	return anomalies.Err()
+}
 
-	err := wgclient.GetParsed("anomalies?status=Finished&count=true", &finishedAnomaliesCount)
+func collectFinishedAnomaliesTotal(ctx context.Context, desc *prometheus.Desc, api *wgapi.Service, ch chan<- prometheus.Metric) error {
+	finishedAnomalies, err := api.FinishedAnomaliesCount(ctx)
 	if err != nil {
-		logging.Error("ParseFloat error: %v", err)
 		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 0)
-		return
+		return err
 	}
 
-	r, err := strconv.ParseFloat(finishedAnomaliesCount.Count, 64)
-	if err != nil {
-		logging.Error("ParseFloat error: %v", err)
+	if !finishedAnomalies.Valid() {
 		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 0)
-		return
+		return &invalidValueError{what: "finished anomalies count", value: finishedAnomalies.String()}
 	}
 
-	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, r)
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, finishedAnomalies.Float64())
+	return nil
 }
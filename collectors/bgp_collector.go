@@ -1,38 +1,24 @@
 package collectors
 
 import (
-	"github.com/tomvil/wanguard_exporter/logging"
+	"context"
+	"errors"
+	"fmt"
 
 	"github.com/prometheus/client_golang/prometheus"
 	wgc "github.com/tomvil/wanguard_exporter/client"
+	"github.com/tomvil/wanguard_exporter/client/wgapi"
 )
 
 type BGPCollector struct {
-	wgClient    *wgc.Client
+	api         *wgapi.Service
 	ConnectorUp *prometheus.Desc
 }
 
-type BGPConnectorList struct {
-	BGPConnectorId   string `json:"bgp_connector_id"`
-	BGPConnectorName string `json:"bgp_connector_name"`
-	Href             string `json:"href"`
-}
-
-type BGPConnectorDetail struct {
-	BGPConnectorId   string `json:"bgp_connector_id"`
-	BGPConnectorName string `json:"bgp_connector_name"`
-	ConnectorRole    string `json:"connector_role"`
-	DeviceGroup      string `json:"device_group"`
-	BGPFlowspec      string `json:"bgp_flowspec"`
-	Status           struct {
-		Href string `json:"href"`
-	} `json:"status"`
-}
-
-func NewBGPCollector(wgclient *wgc.Client) *BGPCollector {
+func NewBGPCollector(wgclient wgc.API) *BGPCollector {
 	prefix := "wanguard_bgp_connector_"
 	return &BGPCollector{
-		wgClient:    wgclient,
+		api:         wgapi.New(wgclient),
 		ConnectorUp: prometheus.NewDesc(prefix+"up", "BGP connector status (1=Active, 0=Down)", []string{"connector_name", "connector_id", "connector_role", "device_group", "flowspec"}, nil),
 	}
 }
@@ -42,31 +28,31 @@ func (c *BGPCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (c *BGPCollector) Collect(ch chan<- prometheus.Metric) {
-	var connectors []BGPConnectorList
+	collect(c, ch)
+}
 
-	err := c.wgClient.GetParsed("bgp_connectors", &connectors)
+func (c *BGPCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	connectors, err := c.api.BGPConnectors(ctx)
 	if err != nil {
-		logging.Error("BGP collector: error fetching connector list: %v", err)
-		return
+		return fmt.Errorf("fetching connector list: %w", err)
 	}
 
+	var errs []error
 	for _, connector := range connectors {
 		// Get detail (includes role, device_group, flowspec)
-		var detail BGPConnectorDetail
-		err := c.wgClient.GetParsed(connector.Href, &detail)
+		detail, err := c.api.BGPConnector(ctx, connector)
 		if err != nil {
-			logging.Error("BGP collector: error fetching detail for %s: %v", connector.BGPConnectorName, err)
+			errs = append(errs, fmt.Errorf("fetching detail for %s: %w", connector.BGPConnectorName, err))
 			continue
 		}
 
 		// Get status
-		var status map[string]string
-		err = c.wgClient.GetParsed(detail.Status.Href, &status)
+		status, err := c.api.Status(ctx, detail.Status.Href)
 		if err != nil {
-			logging.Error("BGP collector: error fetching status for %s: %v", connector.BGPConnectorName, err)
+			errs = append(errs, fmt.Errorf("fetching status for %s: %w", connector.BGPConnectorName, err))
 			ch <- prometheus.MustNewConstMetric(c.ConnectorUp, prometheus.GaugeValue, 0,
 				detail.BGPConnectorName,
-				detail.BGPConnectorId,
+				detail.BGPConnectorID,
 				detail.ConnectorRole,
 				detail.DeviceGroup,
 				detail.BGPFlowspec)
@@ -74,15 +60,17 @@ func (c *BGPCollector) Collect(ch chan<- prometheus.Metric) {
 		}
 
 		value := 0.0
-		if status["status"] == "Active" {
+		if status.Active() {
 			value = 1.0
 		}
 
 		ch <- prometheus.MustNewConstMetric(c.ConnectorUp, prometheus.GaugeValue, value,
 			detail.BGPConnectorName,
-			detail.BGPConnectorId,
+			detail.BGPConnectorID,
 			detail.ConnectorRole,
 			detail.DeviceGroup,
 			detail.BGPFlowspec)
 	}
+
+	return errors.Join(errs...)
 }
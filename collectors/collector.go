@@ -0,0 +1,153 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tomvil/wanguard_exporter/logging"
+)
+
+// ContextCollector is a prometheus.Collector whose WANGuard API calls can be
+// bounded by a context, e.g. the deadline of the scrape that triggered them.
+// CollectContext reports the errors it ran into; the series it could collect
+// are sent nevertheless.
+type ContextCollector interface {
+	prometheus.Collector
+	CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error
+}
+
+type boundCollector struct {
+	ctx       context.Context
+	collector ContextCollector
+	result    func(error)
+}
+
+// WithContext returns a prometheus.Collector that runs c with ctx on every Collect call.
+// Errors are dropped, wrap c with Instrument to report them.
+func WithContext(ctx context.Context, c ContextCollector) prometheus.Collector {
+	return &boundCollector{ctx: ctx, collector: c}
+}
+
+// WithContextResult is like WithContext, but passes the error of every run to result
+func WithContextResult(ctx context.Context, c ContextCollector, result func(error)) prometheus.Collector {
+	return &boundCollector{ctx: ctx, collector: c, result: result}
+}
+
+func (b *boundCollector) Describe(ch chan<- *prometheus.Desc) {
+	b.collector.Describe(ch)
+}
+
+func (b *boundCollector) Collect(ch chan<- prometheus.Metric) {
+	err := b.collector.CollectContext(b.ctx, ch)
+	if b.result != nil {
+		b.result(err)
+	}
+}
+
+// collect runs c without a deadline and logs its errors, to implement prometheus.Collector
+func collect(c ContextCollector, ch chan<- prometheus.Metric) {
+	if err := c.CollectContext(context.Background(), ch); err != nil {
+		logging.Error("Error: %v", err)
+	}
+}
+
+// errorList gathers the errors of a collector run, safe for concurrent use
+type errorList struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (l *errorList) add(err error) {
+	if err == nil {
+		return
+	}
+	l.mu.Lock()
+	l.errs = append(l.errs, err)
+	l.mu.Unlock()
+}
+
+func (l *errorList) err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return errors.Join(l.errs...)
+}
+
+// instrumentation describes the runs of the named collector
+type instrumentation struct {
+	name     string
+	duration *prometheus.Desc
+	success  *prometheus.Desc
+}
+
+func newInstrumentation(name string) instrumentation {
+	// The collector name is a constant label, so that the instrumented collectors can
+	// share a registry without their descriptors colliding
+	labels := prometheus.Labels{"collector": name}
+	return instrumentation{
+		name:     name,
+		duration: prometheus.NewDesc("wanguard_exporter_collector_duration_seconds", "Duration of a collector run", nil, labels),
+		success:  prometheus.NewDesc("wanguard_exporter_collector_success", "Whether a collector run succeeded (1 = success, 0 = failure)", nil, labels),
+	}
+}
+
+func (in instrumentation) describe(ch chan<- *prometheus.Desc) {
+	ch <- in.duration
+	ch <- in.success
+}
+
+// run runs c, logging and counting its errors
+func (in instrumentation) run(ctx context.Context, c ContextCollector, ch chan<- prometheus.Metric) (time.Duration, error) {
+	start := time.Now()
+	err := c.CollectContext(ctx, ch)
+	duration := time.Since(start)
+
+	if err != nil {
+		logging.Error("Collector %s failed after %s: %v", in.name, duration, err)
+		for _, kind := range errorKinds(err) {
+			collectorErrors.WithLabelValues(in.name, kind).Inc()
+		}
+	}
+
+	return duration, err
+}
+
+// result sends the metrics describing a run
+func (in instrumentation) result(duration time.Duration, err error, ch chan<- prometheus.Metric) {
+	success := 1.0
+	if err != nil {
+		success = 0
+	}
+
+	ch <- prometheus.MustNewConstMetric(in.duration, prometheus.GaugeValue, duration.Seconds())
+	ch <- prometheus.MustNewConstMetric(in.success, prometheus.GaugeValue, success)
+}
+
+type instrumentedCollector struct {
+	ContextCollector
+	instrumentation
+}
+
+// Instrument wraps c to export wanguard_exporter_collector_duration_seconds and
+// wanguard_exporter_collector_success for every run, like node_exporter does. Failed
+// runs are logged and counted in wanguard_exporter_collector_errors_total by error kind.
+func Instrument(name string, c ContextCollector) ContextCollector {
+	return &instrumentedCollector{ContextCollector: c, instrumentation: newInstrumentation(name)}
+}
+
+func (ic *instrumentedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ic.ContextCollector.Describe(ch)
+	ic.describe(ch)
+}
+
+func (ic *instrumentedCollector) Collect(ch chan<- prometheus.Metric) {
+	_ = ic.CollectContext(context.Background(), ch)
+}
+
+func (ic *instrumentedCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	duration, err := ic.run(ctx, ic.ContextCollector, ch)
+	ic.result(duration, err, ch)
+	return err
+}
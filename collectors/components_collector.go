@@ -1,22 +1,24 @@
 package collectors
 
 import (
-	"github.com/tomvil/wanguard_exporter/logging"
+	"context"
+	"errors"
 
 	"github.com/prometheus/client_golang/prometheus"
 	wgc "github.com/tomvil/wanguard_exporter/client"
+	"github.com/tomvil/wanguard_exporter/client/wgapi"
 )
 
 type ComponentsCollector struct {
-	wgClient             *wgc.Client
+	api                  *wgapi.Service
 	ComponentsCategories []string
 	ComponentStatus      *prometheus.Desc
 }
 
-func NewComponentsCollector(wgclient *wgc.Client) *ComponentsCollector {
+func NewComponentsCollector(wgclient wgc.API) *ComponentsCollector {
 	prefix := "wanguard_component"
 	return &ComponentsCollector{
-		wgClient:             wgclient,
+		api:                  wgapi.New(wgclient),
 		ComponentsCategories: []string{"bgp_connector", "filter", "sensor"},
 		ComponentStatus:      prometheus.NewDesc(prefix+"status", "Status of the component", []string{"component_name", "component_category"}, nil),
 	}
@@ -27,29 +29,33 @@ func (c *ComponentsCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (c *ComponentsCollector) Collect(ch chan<- prometheus.Metric) {
-	for _, category := range c.ComponentsCategories {
-		var components []map[string]string
+	collect(c, ch)
+}
 
-		err := c.wgClient.GetParsed(category+"s", &components)
+func (c *ComponentsCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	var errs []error
+	for _, category := range c.ComponentsCategories {
+		components, err := c.api.Components(ctx, category)
 		if err != nil {
+			errs = append(errs, err)
 			continue
 		}
 
 		for _, component := range components {
-			var params map[string]string
-
-			err := c.wgClient.GetParsed(component["href"]+"/status", &params)
+			status, err := c.api.ComponentStatus(ctx, component)
 			if err != nil {
-				logging.Error("Error: %v", err)
+				errs = append(errs, err)
 				continue
 			}
 
-			if params["status"] == "Active" {
-				ch <- prometheus.MustNewConstMetric(c.ComponentStatus, prometheus.GaugeValue, 1, component[category+"_name"], category)
+			if status.Active() {
+				ch <- prometheus.MustNewConstMetric(c.ComponentStatus, prometheus.GaugeValue, 1, component.Name, category)
 			} else {
-				ch <- prometheus.MustNewConstMetric(c.ComponentStatus, prometheus.GaugeValue, 0, component[category+"_name"], category)
+				ch <- prometheus.MustNewConstMetric(c.ComponentStatus, prometheus.GaugeValue, 0, component.Name, category)
 			}
 
 		}
 	}
+
+	return errors.Join(errs...)
 }
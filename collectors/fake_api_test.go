@@ -0,0 +1,33 @@
+package collectors
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tomvil/wanguard_exporter/client/wgctest"
+)
+
+func TestLicenseCollectorWithFakeAPI(t *testing.T) {
+	api := wgctest.API{"license_manager": licenseManagerPayload()}
+
+	collector := NewLicenseCollector(api)
+	if count := testutil.CollectAndCount(collector); count != 12 {
+		t.Errorf("Expected 12 metrics, got %d", count)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, family := range families {
+		if family.GetName() == "wanguard_license_license_seconds_remaining" {
+			if v := family.GetMetric()[0].GetGauge().GetValue(); v != 86400 {
+				t.Errorf("Expected 86400 seconds remaining, got %v", v)
+			}
+		}
+	}
+}
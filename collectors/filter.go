@@ -0,0 +1,103 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/tomvil/wanguard_exporter/logging"
+)
+
+// LabelFilter selects the series of a collector by label value
+type LabelFilter struct {
+	keep map[string]*regexp.Regexp
+	drop map[string]*regexp.Regexp
+}
+
+// NewLabelFilter compiles keep and drop, which map label names to regular expressions
+// matching the whole label value. Series are kept if they match all keep expressions and
+// none of the drop expressions; expressions on labels a series does not have are ignored.
+// It returns nil if there is nothing to filter.
+func NewLabelFilter(keep, drop map[string]string) (*LabelFilter, error) {
+	if len(keep) == 0 && len(drop) == 0 {
+		return nil, nil
+	}
+
+	f := &LabelFilter{}
+	var err error
+	if f.keep, err = compileFilters(keep); err != nil {
+		return nil, err
+	}
+	if f.drop, err = compileFilters(drop); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func compileFilters(filters map[string]string) (map[string]*regexp.Regexp, error) {
+	compiled := make(map[string]*regexp.Regexp, len(filters))
+	for label, expr := range filters {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter for label %s: %w", label, err)
+		}
+		compiled[label] = re
+	}
+	return compiled, nil
+}
+
+// allows reports whether a series with the given labels passes the filter
+func (f *LabelFilter) allows(labels []*dto.LabelPair) bool {
+	for _, label := range labels {
+		if re, ok := f.keep[label.GetName()]; ok && !re.MatchString(label.GetValue()) {
+			return false
+		}
+		if re, ok := f.drop[label.GetName()]; ok && re.MatchString(label.GetValue()) {
+			return false
+		}
+	}
+	return true
+}
+
+type filteredCollector struct {
+	ContextCollector
+	filter *LabelFilter
+}
+
+// WithLabelFilter returns a ContextCollector exporting only the series of c that pass f.
+// A nil f returns c unchanged.
+func WithLabelFilter(c ContextCollector, f *LabelFilter) ContextCollector {
+	if f == nil {
+		return c
+	}
+	return &filteredCollector{ContextCollector: c, filter: f}
+}
+
+func (fc *filteredCollector) Collect(ch chan<- prometheus.Metric) {
+	collect(fc, ch)
+}
+
+func (fc *filteredCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	var err error
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		err = fc.ContextCollector.CollectContext(ctx, metrics)
+		close(metrics)
+	}()
+
+	for metric := range metrics {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			logging.Error("Error: %v", err)
+			continue
+		}
+		if fc.filter.allows(m.GetLabel()) {
+			ch <- metric
+		}
+	}
+
+	return err
+}
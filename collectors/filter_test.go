@@ -0,0 +1,79 @@
+package collectors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// sensorsStub emits one series per sensor and one series without labels
+type sensorsStub struct {
+	sensor *prometheus.Desc
+	total  *prometheus.Desc
+}
+
+func newSensorsStub() *sensorsStub {
+	return &sensorsStub{
+		sensor: prometheus.NewDesc("stub_sensor_load", "Load by sensor", []string{"sensor_name"}, nil),
+		total:  prometheus.NewDesc("stub_sensors_total", "Number of sensors", nil, nil),
+	}
+}
+
+func (s *sensorsStub) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.sensor
+	ch <- s.total
+}
+
+func (s *sensorsStub) Collect(ch chan<- prometheus.Metric) {
+	collect(s, ch)
+}
+
+func (s *sensorsStub) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	for _, name := range []string{"edge-1", "edge-2", "core-1", "lab-edge"} {
+		ch <- prometheus.MustNewConstMetric(s.sensor, prometheus.GaugeValue, 1, name)
+	}
+	ch <- prometheus.MustNewConstMetric(s.total, prometheus.GaugeValue, 4)
+	return nil
+}
+
+func TestLabelFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		keep  map[string]string
+		drop  map[string]string
+		count int
+	}{
+		{"keep", map[string]string{"sensor_name": "edge-.*"}, nil, 3},
+		{"drop", nil, map[string]string{"sensor_name": "edge-2|core-1"}, 3},
+		{"keep and drop", map[string]string{"sensor_name": "edge-.*"}, map[string]string{"sensor_name": "edge-2"}, 2},
+		{"unknown label", map[string]string{"country": "LT"}, nil, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewLabelFilter(tt.keep, tt.drop)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count := testutil.CollectAndCount(WithLabelFilter(newSensorsStub(), filter)); count != tt.count {
+				t.Errorf("Expected %d metrics, got %d", tt.count, count)
+			}
+		})
+	}
+}
+
+func TestNewLabelFilter(t *testing.T) {
+	if filter, err := NewLabelFilter(nil, nil); filter != nil || err != nil {
+		t.Errorf("Expected no filter without expressions, got %v, %v", filter, err)
+	}
+	if _, err := NewLabelFilter(map[string]string{"sensor_name": "("}, nil); err == nil {
+		t.Error("Expected error for invalid expression")
+	}
+
+	stub := newSensorsStub()
+	if WithLabelFilter(stub, nil) != ContextCollector(stub) {
+		t.Error("Expected a nil filter to return the collector unchanged")
+	}
+}
@@ -1,26 +1,22 @@
 package collectors
 
 import (
-	"strconv"
+	"context"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tomvil/wanguard_exporter/logging"
 	wgc "github.com/tomvil/wanguard_exporter/client"
+	"github.com/tomvil/wanguard_exporter/client/wgapi"
 )
 
 type FirewallRulesCollector struct {
-	wgClient           *wgc.Client
+	api                *wgapi.Service
 	FirewallRuleActive *prometheus.Desc
 }
 
-type FirewallRulesCount struct {
-	Count string
-}
-
-func NewFirewallRulesCollector(wgclient *wgc.Client) *FirewallRulesCollector {
+func NewFirewallRulesCollector(wgclient wgc.API) *FirewallRulesCollector {
 	prefix := "wanguard_firewall_rule_"
 	return &FirewallRulesCollector{
-		wgClient:           wgclient,
+		api:                wgapi.New(wgclient),
 		FirewallRuleActive: prometheus.NewDesc(prefix+"active", "Active firewall rules", []string{"firewall_rule_name"}, nil),
 	}
 }
@@ -30,20 +26,20 @@ func (c *FirewallRulesCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (c *FirewallRulesCollector) Collect(ch chan<- prometheus.Metric) {
-	var firewallRulesCount FirewallRulesCount
+	collect(c, ch)
+}
 
-	err := c.wgClient.GetParsed("firewall_rules?count=true", &firewallRulesCount)
+func (c *FirewallRulesCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	rulesCount, err := c.api.FirewallRulesCount(ctx)
 	if err != nil {
-		logging.Error("Error: %v", err)
-		return
+		return err
 	}
 
-	rulesCount, err := strconv.ParseFloat(firewallRulesCount.Count, 64)
-	if err != nil {
-		logging.Error("Error: %v", err)
+	if !rulesCount.Valid() {
 		ch <- prometheus.MustNewConstMetric(c.FirewallRuleActive, prometheus.GaugeValue, 0)
-		return
+		return &invalidValueError{what: "firewall rules count", value: rulesCount.String()}
 	}
 
-	ch <- prometheus.MustNewConstMetric(c.FirewallRuleActive, prometheus.GaugeValue, rulesCount)
+	ch <- prometheus.MustNewConstMetric(c.FirewallRuleActive, prometheus.GaugeValue, rulesCount.Float64())
+	return nil
 }
@@ -3,49 +3,22 @@ package collectors
 import (
 	"github.com/tomvil/wanguard_exporter/logging"
 
-	"strings"
-	"strconv"
+	"github.com/tomvil/wanguard_exporter/client/wgapi"
 )
 
 func bitsToBytes(b float64) float64 {
 	return b / 8
 }
 
-func getFloat64(v interface{}) float64 {
-	switch v := v.(type) {
-	case float64:
-		return v
-	case int:
-		return float64(v)
-	case nil:
-		return 0
-	case string:
-		r := strings.NewReplacer(
-			" days", "",
-			"∞", "9999")
-
-		result, err := strconv.ParseFloat(r.Replace(v), 64)
-		if err != nil {
-			logging.Error("was not able to parse %T to float64!", v)
-			return 0
-		}
-
-		return float64(result)
-	default:
-		logging.Error("conversion to float64 from %T is not supported", v)
-		return 0
+// getFloat64 returns the value of n, logging values the API sent but that are not numeric
+func getFloat64(n wgapi.Number) float64 {
+	if !n.Valid() && n.String() != "" {
+		logging.Error("was not able to parse %q to float64!", n.String())
 	}
+
+	return n.Float64()
 }
 
 func toSeconds(days float64) float64 {
 	return days * 86400
 }
-
-func stringToFloat64(v string) float64 {
-	f, err := strconv.ParseFloat(v, 64)
-	if err != nil {
-		return 0
-	}
-
-	return f
-}
@@ -1,14 +1,15 @@
 package collectors
 
 import (
-	"github.com/tomvil/wanguard_exporter/logging"
+	"context"
 
 	"github.com/prometheus/client_golang/prometheus"
 	wgc "github.com/tomvil/wanguard_exporter/client"
+	"github.com/tomvil/wanguard_exporter/client/wgapi"
 )
 
 type LicenseCollector struct {
-	wgClient                       *wgc.Client
+	api                            *wgapi.Service
 	SoftwareVersion                *prometheus.Desc
 	LicensedSensors                *prometheus.Desc
 	LicensedSensorsUsed            *prometheus.Desc
@@ -23,25 +24,10 @@ type LicenseCollector struct {
 	LicenseSupportSecondsRemaining *prometheus.Desc
 }
 
-type License struct {
-	SoftwareVersion              string      `json:"software_version"`
-	LicensedSensors              interface{} `json:"licensed_sensors"`
-	LicensedSensorsUsed          interface{} `json:"licensed_sensors_used"`
-	LicensedSensorsRemaining     interface{} `json:"licensed_sensors_remaining"`
-	LicensedDpdkEngines          interface{} `json:"licensed_dpdk_engines"`
-	LicensedDpdkEnginesUsed      interface{} `json:"licensed_dpdk_engines_used"`
-	LicensedDpdkEnginesRemaining interface{} `json:"licensed_dpdk_engines_remaining"`
-	LicensedFilters              interface{} `json:"licensed_filters"`
-	LicensedFiltersUsed          interface{} `json:"licensed_filters_used"`
-	LicensedFiltersRemaining     interface{} `json:"licensed_filters_remaining"`
-	LicenseDaysRemaining         interface{} `json:"license_expiry_date_remaining"`
-	LicenseSupportDaysRemaining  interface{} `json:"support_expiry_date_remaining"`
-}
-
-func NewLicenseCollector(wgclient *wgc.Client) *LicenseCollector {
+func NewLicenseCollector(wgclient wgc.API) *LicenseCollector {
 	prefix := "wanguard_license_"
 	return &LicenseCollector{
-		wgClient:                       wgclient,
+		api:                            wgapi.New(wgclient),
 		SoftwareVersion:                prometheus.NewDesc(prefix+"software_version", "Software version", []string{"software_version"}, nil),
 		LicensedSensors:                prometheus.NewDesc(prefix+"sensors_available", "Licensed sensors available", nil, nil),
 		LicensedSensorsUsed:            prometheus.NewDesc(prefix+"sensors_used", "Licensed sensors used", nil, nil),
@@ -73,12 +59,13 @@ func (c *LicenseCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (c *LicenseCollector) Collect(ch chan<- prometheus.Metric) {
-	var license License
+	collect(c, ch)
+}
 
-	err := c.wgClient.GetParsed("license_manager", &license)
+func (c *LicenseCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	license, err := c.api.License(ctx)
 	if err != nil {
-		logging.Error("Error: %v", err)
-		return
+		return err
 	}
 
 	ch <- prometheus.MustNewConstMetric(c.SoftwareVersion, prometheus.GaugeValue, 1, license.SoftwareVersion)
@@ -91,6 +78,8 @@ func (c *LicenseCollector) Collect(ch chan<- prometheus.Metric) {
 	ch <- prometheus.MustNewConstMetric(c.LicensedFilters, prometheus.GaugeValue, getFloat64(license.LicensedFilters))
 	ch <- prometheus.MustNewConstMetric(c.LicensedFiltersUsed, prometheus.GaugeValue, getFloat64(license.LicensedFiltersUsed))
 	ch <- prometheus.MustNewConstMetric(c.LicensedFiltersRemaining, prometheus.GaugeValue, getFloat64(license.LicensedFiltersRemaining))
-	ch <- prometheus.MustNewConstMetric(c.LicenseSecondsRemaining, prometheus.GaugeValue, getFloat64(license.LicenseDaysRemaining)*86400)
-	ch <- prometheus.MustNewConstMetric(c.LicenseSupportSecondsRemaining, prometheus.GaugeValue, getFloat64(license.LicenseSupportDaysRemaining)*86400)
+	ch <- prometheus.MustNewConstMetric(c.LicenseSecondsRemaining, prometheus.GaugeValue, toSeconds(getFloat64(license.LicenseDaysRemaining)))
+	ch <- prometheus.MustNewConstMetric(c.LicenseSupportSecondsRemaining, prometheus.GaugeValue, toSeconds(getFloat64(license.SupportDaysRemaining)))
+
+	return nil
 }
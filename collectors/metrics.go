@@ -0,0 +1,98 @@
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	wgc "github.com/tomvil/wanguard_exporter/client"
+)
+
+// Metrics describing the health of the collectors
+var (
+	collectorErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "wanguard_exporter_collector_errors_total",
+			Help: "Number of errors collectors ran into by collector and error kind",
+		},
+		[]string{"collector", "kind"},
+	)
+)
+
+// InitMetrics returns the collector metrics to be registered by the exporter
+func InitMetrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		collectorErrors,
+	}
+}
+
+// invalidValueError is returned when the API sent a value the collector cannot export
+type invalidValueError struct {
+	what  string
+	value string
+}
+
+func (e *invalidValueError) Error() string {
+	return fmt.Sprintf("invalid %s %q", e.what, e.value)
+}
+
+// errorKinds returns the distinct kinds of the errors joined in err, used as the kind
+// label of wanguard_exporter_collector_errors_total
+func errorKinds(err error) []string {
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	} else {
+		errs = []error{err}
+	}
+
+	seen := make(map[string]bool)
+	var kinds []string
+	for _, err := range errs {
+		if kind := errorKind(err); !seen[kind] {
+			seen[kind] = true
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// errorKind classifies err into a small set of kinds
+func errorKind(err error) string {
+	var (
+		apiErr         *wgc.APIError
+		netErr         *wgc.NetworkError
+		tooLargeErr    *wgc.ResponseTooLargeError
+		unsupportedErr *wgc.UnsupportedEndpointError
+		syntaxErr      *json.SyntaxError
+		typeErr        *json.UnmarshalTypeError
+		invalidErr     *invalidValueError
+	)
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, wgc.ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, wgc.ErrRateLimited):
+		return "rate_limited"
+	case errors.As(err, &unsupportedErr):
+		return "unsupported"
+	case errors.As(err, &tooLargeErr):
+		return "response_too_large"
+	case errors.As(err, &apiErr) && apiErr.Unauthorized():
+		return "unauthorized"
+	case errors.As(err, &apiErr):
+		return "http"
+	case errors.As(err, &netErr):
+		return "network"
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.As(err, &invalidErr):
+		return "parse"
+	default:
+		return "other"
+	}
+}
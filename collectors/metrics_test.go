@@ -0,0 +1,89 @@
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	wgc "github.com/tomvil/wanguard_exporter/client"
+	"github.com/tomvil/wanguard_exporter/client/wgctest"
+)
+
+func TestInstrument(t *testing.T) {
+	collector := Instrument("license_ok", NewLicenseCollector(wgctest.API{"license_manager": licenseManagerPayload()}))
+
+	expected := `
+# HELP wanguard_exporter_collector_success Whether a collector run succeeded (1 = success, 0 = failure)
+# TYPE wanguard_exporter_collector_success gauge
+wanguard_exporter_collector_success{collector="license_ok"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "wanguard_exporter_collector_success"); err != nil {
+		t.Error(err)
+	}
+	if count := testutil.CollectAndCount(collector, "wanguard_exporter_collector_duration_seconds"); count != 1 {
+		t.Errorf("Expected 1 duration series, got %d", count)
+	}
+	if count := testutil.CollectAndCount(collector); count != 14 {
+		t.Errorf("Expected 12 license metrics plus 2 collector metrics, got %d", count)
+	}
+}
+
+func TestInstrumentFailure(t *testing.T) {
+	collector := Instrument("license_failing", NewLicenseCollector(wgctest.API{}))
+	errorsBefore := testutil.ToFloat64(collectorErrors.WithLabelValues("license_failing", "http"))
+
+	expected := `
+# HELP wanguard_exporter_collector_success Whether a collector run succeeded (1 = success, 0 = failure)
+# TYPE wanguard_exporter_collector_success gauge
+wanguard_exporter_collector_success{collector="license_failing"} 0
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "wanguard_exporter_collector_success"); err != nil {
+		t.Error(err)
+	}
+
+	if v := testutil.ToFloat64(collectorErrors.WithLabelValues("license_failing", "http")) - errorsBefore; v != 1 {
+		t.Errorf("Expected 1 more http error, got %v", v)
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	var syntaxErr *json.SyntaxError
+	parseErr := wgctest.API{"license_manager": "{"}.GetParsedContext(context.Background(), "license_manager", &struct{}{})
+	if !errors.As(parseErr, &syntaxErr) {
+		t.Fatalf("Expected a JSON syntax error, got %v", parseErr)
+	}
+
+	tests := []struct {
+		err   error
+		kinds []string
+	}{
+		{context.DeadlineExceeded, []string{"timeout"}},
+		{fmt.Errorf("fetching: %w", context.Canceled), []string{"canceled"}},
+		{wgc.ErrCircuitOpen, []string{"circuit_open"}},
+		{fmt.Errorf("%w: rate exceeded", wgc.ErrRateLimited), []string{"rate_limited"}},
+		{&wgc.UnsupportedEndpointError{Endpoint: "bgp_connectors"}, []string{"unsupported"}},
+		{&wgc.ResponseTooLargeError{Endpoint: "anomalies"}, []string{"response_too_large"}},
+		{&wgc.APIError{Endpoint: "sensors", StatusCode: 401}, []string{"unauthorized"}},
+		{&wgc.APIError{Endpoint: "sensors", StatusCode: 500}, []string{"http"}},
+		{&wgc.NetworkError{Err: errors.New("connection refused")}, []string{"network"}},
+		{parseErr, []string{"parse"}},
+		{&invalidValueError{what: "count", value: "n/a"}, []string{"parse"}},
+		{errors.New("boom"), []string{"other"}},
+		{errors.Join(
+			&wgc.APIError{Endpoint: "sensors", StatusCode: 500},
+			context.DeadlineExceeded,
+			&wgc.APIError{Endpoint: "filters", StatusCode: 503},
+		), []string{"http", "timeout"}},
+	}
+
+	for _, test := range tests {
+		if kinds := errorKinds(test.err); !reflect.DeepEqual(kinds, test.kinds) {
+			t.Errorf("errorKinds(%v) = %v, expected %v", test.err, kinds, test.kinds)
+		}
+	}
+}
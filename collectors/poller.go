@@ -0,0 +1,157 @@
+package collectors
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultStaleIntervals is the number of poll intervals after which a snapshot is stale
+// when no stale threshold is given
+const DefaultStaleIntervals = 3
+
+// Poller runs a collector in the background and serves the series of its latest run,
+// so that scrapes do not wait for the WANGuard API and several Prometheus servers do not
+// multiply the load on it.
+type Poller struct {
+	collector  ContextCollector
+	interval   time.Duration
+	staleAfter time.Duration
+	instrumentation
+
+	age   *prometheus.Desc
+	stale *prometheus.Desc
+
+	mu       sync.RWMutex
+	snapshot []prometheus.Metric
+	updated  time.Time
+	duration time.Duration
+	err      error
+	polled   bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPoller returns a Poller running c every interval once started. A snapshot older than
+// staleAfter, DefaultStaleIntervals intervals if zero, is not served anymore.
+func NewPoller(name string, c ContextCollector, interval, staleAfter time.Duration) *Poller {
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleIntervals * interval
+	}
+
+	labels := prometheus.Labels{"collector": name}
+	return &Poller{
+		collector:       c,
+		interval:        interval,
+		staleAfter:      staleAfter,
+		instrumentation: newInstrumentation(name),
+		age:             prometheus.NewDesc("wanguard_exporter_collector_data_age_seconds", "Age of the series served by a background polled collector", nil, labels),
+		stale:           prometheus.NewDesc("wanguard_exporter_collector_data_stale", "Whether the series of a background polled collector are older than its stale threshold and not served (1 = stale, 0 = fresh)", nil, labels),
+	}
+}
+
+// Start polls in the background until Stop is called, beginning immediately
+func (p *Poller) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			p.poll(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops polling and waits for a running poll to finish
+func (p *Poller) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+}
+
+// poll runs the collector once, bounded by the poll interval. The snapshot is replaced
+// unless the run failed without any series, e.g. while the API is unreachable, in which
+// case the previous snapshot is kept and ages until it is stale.
+func (p *Poller) poll(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, p.interval)
+	defer cancel()
+
+	var metrics []prometheus.Metric
+	ch := make(chan prometheus.Metric)
+	finished := make(chan struct{})
+	go func() {
+		for metric := range ch {
+			metrics = append(metrics, metric)
+		}
+		close(finished)
+	}()
+
+	duration, err := p.run(ctx, p.collector, ch)
+	close(ch)
+	<-finished
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.duration, p.err, p.polled = duration, err, true
+	if err == nil || len(metrics) > 0 {
+		p.snapshot = metrics
+		p.updated = time.Now()
+	}
+}
+
+func (p *Poller) Describe(ch chan<- *prometheus.Desc) {
+	p.collector.Describe(ch)
+	p.describe(ch)
+	ch <- p.age
+	ch <- p.stale
+}
+
+func (p *Poller) Collect(ch chan<- prometheus.Metric) {
+	_ = p.CollectContext(context.Background(), ch)
+}
+
+// CollectContext sends the latest snapshot, ctx is not used as the API is not contacted.
+// Errors of the background runs are reported by wanguard_exporter_collector_success.
+func (p *Poller) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.polled {
+		p.result(p.duration, p.err, ch)
+	}
+
+	if p.updated.IsZero() {
+		ch <- prometheus.MustNewConstMetric(p.stale, prometheus.GaugeValue, 1)
+		return nil
+	}
+
+	age := time.Since(p.updated)
+	ch <- prometheus.MustNewConstMetric(p.age, prometheus.GaugeValue, age.Seconds())
+	if age > p.staleAfter {
+		ch <- prometheus.MustNewConstMetric(p.stale, prometheus.GaugeValue, 1)
+		return nil
+	}
+
+	ch <- prometheus.MustNewConstMetric(p.stale, prometheus.GaugeValue, 0)
+	for _, metric := range p.snapshot {
+		ch <- metric
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package collectors
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tomvil/wanguard_exporter/client/wgctest"
+)
+
+func TestPoller(t *testing.T) {
+	api := wgctest.API{"license_manager": licenseManagerPayload()}
+	poller := NewPoller("license_polled", NewLicenseCollector(api), time.Minute, 0)
+
+	expected := `
+# HELP wanguard_exporter_collector_data_stale Whether the series of a background polled collector are older than its stale threshold and not served (1 = stale, 0 = fresh)
+# TYPE wanguard_exporter_collector_data_stale gauge
+wanguard_exporter_collector_data_stale{collector="license_polled"} 1
+`
+	if err := testutil.CollectAndCompare(poller, strings.NewReader(expected), "wanguard_exporter_collector_data_stale"); err != nil {
+		t.Errorf("Before the first poll: %v", err)
+	}
+	if count := testutil.CollectAndCount(poller); count != 1 {
+		t.Errorf("Expected only the stale series before the first poll, got %d", count)
+	}
+
+	poller.poll(context.Background())
+	// 12 license metrics, duration, success, age and stale
+	if count := testutil.CollectAndCount(poller); count != 16 {
+		t.Errorf("Expected 16 metrics, got %d", count)
+	}
+
+	// A failed poll without series keeps the previous snapshot
+	delete(api, "license_manager")
+	poller.poll(context.Background())
+	if count := testutil.CollectAndCount(poller, "wanguard_license_sensors_available"); count != 1 {
+		t.Errorf("Expected the previous snapshot to be served, got %d series", count)
+	}
+	expected = `
+# HELP wanguard_exporter_collector_success Whether a collector run succeeded (1 = success, 0 = failure)
+# TYPE wanguard_exporter_collector_success gauge
+wanguard_exporter_collector_success{collector="license_polled"} 0
+`
+	if err := testutil.CollectAndCompare(poller, strings.NewReader(expected), "wanguard_exporter_collector_success"); err != nil {
+		t.Error(err)
+	}
+
+	// Snapshots older than 3 intervals are not served
+	poller.mu.Lock()
+	poller.updated = time.Now().Add(-4 * time.Minute)
+	poller.mu.Unlock()
+	if count := testutil.CollectAndCount(poller, "wanguard_license_sensors_available"); count != 0 {
+		t.Errorf("Expected the stale snapshot to be dropped, got %d series", count)
+	}
+	expected = `
+# HELP wanguard_exporter_collector_data_stale Whether the series of a background polled collector are older than its stale threshold and not served (1 = stale, 0 = fresh)
+# TYPE wanguard_exporter_collector_data_stale gauge
+wanguard_exporter_collector_data_stale{collector="license_polled"} 1
+`
+	if err := testutil.CollectAndCompare(poller, strings.NewReader(expected), "wanguard_exporter_collector_data_stale"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPollerStartStop(t *testing.T) {
+	poller := NewPoller("license_background", NewLicenseCollector(wgctest.API{"license_manager": licenseManagerPayload()}), 10*time.Millisecond, time.Minute)
+	poller.Start()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.CollectAndCount(poller, "wanguard_license_sensors_available") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a snapshot from the background poll")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	poller.Stop()
+	poller.Stop()
+}
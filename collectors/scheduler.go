@@ -0,0 +1,207 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tomvil/wanguard_exporter/logging"
+)
+
+// cancelGrace is how long a cancelled collector is given to return its error before the
+// scheduler abandons it
+const cancelGrace = 200 * time.Millisecond
+
+// Job is a collector run by a Scheduler, normally wrapped by Instrument. A zero Timeout
+// bounds the run by the scrape only.
+type Job struct {
+	Name      string
+	Collector ContextCollector
+	Timeout   time.Duration
+}
+
+// Scheduler runs collectors in parallel on a bounded number of workers, each within its
+// own timeout, so that one slow collector cannot hold back the others
+type Scheduler struct {
+	workers int
+	jobs    []Job
+
+	partial  *prometheus.Desc
+	timedOut *prometheus.Desc
+}
+
+// NewScheduler returns a Scheduler running jobs on at most workers at once (0 = all at once)
+func NewScheduler(workers int, jobs []Job) *Scheduler {
+	return &Scheduler{
+		workers:  workers,
+		jobs:     jobs,
+		partial:  prometheus.NewDesc("wanguard_exporter_scrape_partial", "Whether collectors timed out during the scrape, so that only part of the series were collected (1 = partial, 0 = complete)", nil, nil),
+		timedOut: prometheus.NewDesc("wanguard_exporter_scrape_collector_timeout", "Whether a collector timed out during the scrape (1 = timed out, 0 = finished in time)", []string{"collector"}, nil),
+	}
+}
+
+// jobResult is the outcome of a job. Its series are buffered, so that a job abandoned at its
+// deadline can keep sending without blocking anybody; err and timedOut are set by the worker.
+type jobResult struct {
+	mu        sync.Mutex
+	metrics   []prometheus.Metric
+	abandoned bool
+
+	err      error
+	timedOut bool
+}
+
+func (r *jobResult) add(metric prometheus.Metric) {
+	r.mu.Lock()
+	if !r.abandoned {
+		r.metrics = append(r.metrics, metric)
+	}
+	r.mu.Unlock()
+}
+
+// abandon stops accepting series from the job and adds the metrics describing its run in
+// place of the ones Instrument would have sent on return
+func (r *jobResult) abandon(name string, duration time.Duration, err error) {
+	ch := make(chan prometheus.Metric, 2)
+	newInstrumentation(name).result(duration, err, ch)
+	close(ch)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.abandoned = true
+	for metric := range ch {
+		r.metrics = append(r.metrics, metric)
+	}
+}
+
+// collected returns the series sent so far
+func (r *jobResult) collected() []prometheus.Metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]prometheus.Metric(nil), r.metrics...)
+}
+
+func (s *Scheduler) Describe(ch chan<- *prometheus.Desc) {
+	for _, job := range s.jobs {
+		job.Collector.Describe(ch)
+		// Sent by the scheduler for abandoned jobs, already described if the job is instrumented
+		newInstrumentation(job.Name).describe(ch)
+	}
+	ch <- s.partial
+	ch <- s.timedOut
+}
+
+func (s *Scheduler) Collect(ch chan<- prometheus.Metric) {
+	collect(s, ch)
+}
+
+// CollectContext runs the jobs and sends the series of all of them, including what the
+// timed out ones sent before their deadline. It returns the errors of the jobs.
+func (s *Scheduler) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	workers := s.workers
+	if workers <= 0 || workers > len(s.jobs) {
+		workers = len(s.jobs)
+	}
+	slots := make(chan struct{}, workers)
+
+	results := make([]*jobResult, len(s.jobs))
+	var wg sync.WaitGroup
+	for i, job := range s.jobs {
+		results[i] = &jobResult{}
+		wg.Add(1)
+		go func(job Job, result *jobResult) {
+			defer wg.Done()
+
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				// The scrape ended while the job was queued
+				result.timedOut, result.err = true, fmt.Errorf("collector %s not started: %w", job.Name, ctx.Err())
+				result.abandon(job.Name, 0, result.err)
+				return
+			}
+			result.timedOut, result.err = s.run(ctx, job, result)
+		}(job, results[i])
+	}
+	wg.Wait()
+
+	var errs []error
+	var timedOut []string
+	for i, result := range results {
+		for _, metric := range result.collected() {
+			ch <- metric
+		}
+
+		value := 0.0
+		if result.timedOut {
+			value = 1
+			timedOut = append(timedOut, s.jobs[i].Name)
+		}
+		ch <- prometheus.MustNewConstMetric(s.timedOut, prometheus.GaugeValue, value, s.jobs[i].Name)
+
+		if result.err != nil {
+			errs = append(errs, result.err)
+		}
+	}
+
+	partial := 0.0
+	if len(timedOut) > 0 {
+		partial = 1
+		logging.Warn("Partial scrape, collectors timed out: %v", timedOut)
+	}
+	ch <- prometheus.MustNewConstMetric(s.partial, prometheus.GaugeValue, partial)
+
+	return errors.Join(errs...)
+}
+
+// run runs job within its timeout. A collector that does not return within cancelGrace of
+// its deadline is abandoned and reported as failed; it finishes in the background once its
+// API requests see the cancellation.
+func (s *Scheduler) run(ctx context.Context, job Job, result *jobResult) (timedOut bool, err error) {
+	start := time.Now()
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
+	metrics := make(chan prometheus.Metric)
+	done := make(chan error, 1)
+	go func() {
+		done <- job.Collector.CollectContext(ctx, metrics)
+		close(metrics)
+	}()
+
+	drained := make(chan struct{})
+	go func() {
+		for metric := range metrics {
+			result.add(metric)
+		}
+		close(drained)
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		grace := time.NewTimer(cancelGrace)
+		defer grace.Stop()
+
+		select {
+		case err = <-done:
+		case <-grace.C:
+			logging.Warn("Collector %s did not finish in time, serving the series it sent so far", job.Name)
+			err = fmt.Errorf("collector %s abandoned: %w", job.Name, ctx.Err())
+			result.abandon(job.Name, time.Since(start), err)
+			return true, err
+		}
+	}
+
+	<-drained
+	return errors.Is(err, context.DeadlineExceeded), err
+}
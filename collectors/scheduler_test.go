@@ -0,0 +1,177 @@
+package collectors
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// slowStub sends one series, then waits for delay or, if it honors it, the context
+type slowStub struct {
+	desc        *prometheus.Desc
+	delay       time.Duration
+	honorCtx    bool
+	running     *atomic.Int32
+	maxParallel *atomic.Int32
+}
+
+func newSlowStub(name string, delay time.Duration, honorCtx bool) *slowStub {
+	return &slowStub{
+		desc:     prometheus.NewDesc("stub_"+name, "Stub series", nil, nil),
+		delay:    delay,
+		honorCtx: honorCtx,
+	}
+}
+
+func (s *slowStub) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.desc
+}
+
+func (s *slowStub) Collect(ch chan<- prometheus.Metric) {
+	collect(s, ch)
+}
+
+func (s *slowStub) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	if s.running != nil {
+		n := s.running.Add(1)
+		defer s.running.Add(-1)
+		for max := s.maxParallel.Load(); n > max && !s.maxParallel.CompareAndSwap(max, n); max = s.maxParallel.Load() {
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(s.desc, prometheus.GaugeValue, 1)
+
+	if !s.honorCtx {
+		time.Sleep(s.delay)
+		return nil
+	}
+	select {
+	case <-time.After(s.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	scheduler := NewScheduler(0, []Job{
+		{Name: "fast", Collector: newSlowStub("fast", 0, true)},
+		{Name: "slow", Collector: newSlowStub("slow", time.Minute, true), Timeout: 20 * time.Millisecond},
+		{Name: "stuck", Collector: newSlowStub("stuck", time.Second, false), Timeout: 20 * time.Millisecond},
+	})
+
+	start := time.Now()
+	expected := `
+# HELP stub_fast Stub series
+# TYPE stub_fast gauge
+stub_fast 1
+# HELP stub_slow Stub series
+# TYPE stub_slow gauge
+stub_slow 1
+# HELP stub_stuck Stub series
+# TYPE stub_stuck gauge
+stub_stuck 1
+# HELP wanguard_exporter_scrape_collector_timeout Whether a collector timed out during the scrape (1 = timed out, 0 = finished in time)
+# TYPE wanguard_exporter_scrape_collector_timeout gauge
+wanguard_exporter_scrape_collector_timeout{collector="fast"} 0
+wanguard_exporter_scrape_collector_timeout{collector="slow"} 1
+wanguard_exporter_scrape_collector_timeout{collector="stuck"} 1
+# HELP wanguard_exporter_scrape_partial Whether collectors timed out during the scrape, so that only part of the series were collected (1 = partial, 0 = complete)
+# TYPE wanguard_exporter_scrape_partial gauge
+wanguard_exporter_scrape_partial 1
+`
+	names := []string{"stub_fast", "stub_slow", "stub_stuck", "wanguard_exporter_scrape_collector_timeout", "wanguard_exporter_scrape_partial"}
+	if err := testutil.CollectAndCompare(scheduler, strings.NewReader(expected), names...); err != nil {
+		t.Error(err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the timed out collectors to be abandoned, the scrape took %s", elapsed)
+	}
+}
+
+func TestSchedulerWorkers(t *testing.T) {
+	var running, maxParallel atomic.Int32
+	var jobs []Job
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		stub := newSlowStub(name, 20*time.Millisecond, true)
+		stub.running, stub.maxParallel = &running, &maxParallel
+		jobs = append(jobs, Job{Name: name, Collector: stub})
+	}
+
+	if count := testutil.CollectAndCount(NewScheduler(2, jobs), "wanguard_exporter_scrape_collector_timeout"); count != 5 {
+		t.Errorf("Expected 5 collectors to be reported, got %d", count)
+	}
+	if max := maxParallel.Load(); max != 2 {
+		t.Errorf("Expected 2 collectors to run in parallel, got %d", max)
+	}
+}
+
+func TestSchedulerScrapeTimeout(t *testing.T) {
+	// Whichever job runs first holds the only worker until the scrape times out
+	scheduler := NewScheduler(1, []Job{
+		{Name: "slow", Collector: newSlowStub("slow", time.Minute, true)},
+		{Name: "queued", Collector: newSlowStub("queued", time.Minute, true)},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	ch := make(chan prometheus.Metric, 10)
+	if err := scheduler.CollectContext(ctx, ch); err == nil {
+		t.Error("Expected an error for the timed out collectors")
+	}
+	close(ch)
+
+	// The job that ran returns in time and is not instrumented, the queued one is reported failed
+	if len(ch) != 6 {
+		t.Errorf("Expected the series of the job that ran, the duration and success of the queued job, 2 timeout series and the partial series, got %d", len(ch))
+	}
+}
+
+func TestSchedulerReportsTimedOutCollectors(t *testing.T) {
+	// slow returns its context error once cancelled, stuck ignores the cancellation and
+	// is abandoned after the grace period
+	scheduler := NewScheduler(0, []Job{
+		{Name: "slow", Collector: Instrument("slow", newSlowStub("slow", time.Minute, true)), Timeout: 50 * time.Millisecond},
+		{Name: "stuck", Collector: Instrument("stuck", newSlowStub("stuck", time.Second, false)), Timeout: 50 * time.Millisecond},
+	})
+
+	expected := `
+# HELP wanguard_exporter_collector_success Whether a collector run succeeded (1 = success, 0 = failure)
+# TYPE wanguard_exporter_collector_success gauge
+wanguard_exporter_collector_success{collector="slow"} 0
+wanguard_exporter_collector_success{collector="stuck"} 0
+`
+	if err := testutil.CollectAndCompare(scheduler, strings.NewReader(expected), "wanguard_exporter_collector_success"); err != nil {
+		t.Error(err)
+	}
+
+	ch := make(chan prometheus.Metric, 20)
+	if err := scheduler.CollectContext(context.Background(), ch); err == nil {
+		t.Error("Expected an error for the timed out collectors")
+	}
+	close(ch)
+
+	durations := 0
+	for metric := range ch {
+		if strings.Contains(metric.Desc().String(), `"wanguard_exporter_collector_duration_seconds"`) {
+			var m dto.Metric
+			if err := metric.Write(&m); err != nil {
+				t.Fatal(err)
+			}
+			if m.GetGauge().GetValue() < 0.05 {
+				t.Errorf("Expected the duration to cover the timeout, got %v", m.GetGauge().GetValue())
+			}
+			durations++
+		}
+	}
+	if durations != 2 {
+		t.Errorf("Expected a duration for both collectors, got %d", durations)
+	}
+}
@@ -1,15 +1,15 @@
 package collectors
 
 import (
-	"github.com/tomvil/wanguard_exporter/logging"
-
+	"context"
 
 	"github.com/prometheus/client_golang/prometheus"
 	wgc "github.com/tomvil/wanguard_exporter/client"
+	"github.com/tomvil/wanguard_exporter/client/wgapi"
 )
 
 type SensorsCollector struct {
-	wgClient          *wgc.Client
+	api               *wgapi.Service
 	SensorInternalIPS *prometheus.Desc
 	SensorExternalIPS *prometheus.Desc
 	SensorPPSIn       *prometheus.Desc
@@ -25,30 +25,10 @@ type SensorsCollector struct {
 	SensorRam         *prometheus.Desc
 }
 
-type Sensor struct {
-	Sensor struct {
-		InterfaceName string `json:"sensor_interface_name"`
-		InterfaceID   string `json:"sensor_interface_id"`
-	}
-	InternalIPS         string `json:"internal_ips"`
-	ExternalIPS         string `json:"external_ips"`
-	PacketsPerSecondIN  string `json:"packets/s_in"`
-	PacketsPerSecondOUT string `json:"packets/s_out"`
-	BitsPerSecondIN     string `json:"bits/s_in"`
-	BitsPerSecondOUT    string `json:"bits/s_out"`
-	DroppedIN           string `json:"dropped_in"`
-	DroppedOUT          string `json:"dropped_out"`
-	UsageIN             string `json:"usage_in"`
-	UsageOUT            string `json:"usage_out"`
-	Load                string
-	Cpu                 string `json:"cpu%"`
-	Ram                 int
-}
-
-func NewSensorsCollector(wgclient *wgc.Client) *SensorsCollector {
+func NewSensorsCollector(wgclient wgc.API) *SensorsCollector {
 	prefix := "wanguard_sensor"
 	return &SensorsCollector{
-		wgClient:          wgclient,
+		api:               wgapi.New(wgclient),
 		SensorInternalIPS: prometheus.NewDesc(prefix+"internal_ips", "Total number of internal ip addresses", []string{"sensor_name", "sensor_id"}, nil),
 		SensorExternalIPS: prometheus.NewDesc(prefix+"external_ips", "Total number of external ip addresses", []string{"sensor_name", "sensor_id"}, nil),
 		SensorPPSIn:       prometheus.NewDesc(prefix+"packets_per_second_in", "Incoming packets per second", []string{"sensor_name", "sensor_id"}, nil),
@@ -82,26 +62,27 @@ func (c *SensorsCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (c *SensorsCollector) Collect(ch chan<- prometheus.Metric) {
-	var sensors []Sensor
+	collect(c, ch)
+}
 
-	err := c.wgClient.GetParsed("sensor_live_stats", &sensors)
-	if err != nil {
-		logging.Error("Error: %v", err)
-	}
+func (c *SensorsCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
+	sensors, err := c.api.SensorLiveStats(ctx)
 
 	for _, s := range sensors {
-		ch <- prometheus.MustNewConstMetric(c.SensorInternalIPS, prometheus.GaugeValue, stringToFloat64(s.InternalIPS), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
-		ch <- prometheus.MustNewConstMetric(c.SensorExternalIPS, prometheus.GaugeValue, stringToFloat64(s.ExternalIPS), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
-		ch <- prometheus.MustNewConstMetric(c.SensorPPSIn, prometheus.GaugeValue, stringToFloat64(s.PacketsPerSecondIN), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
-		ch <- prometheus.MustNewConstMetric(c.SensorPPSOut, prometheus.GaugeValue, stringToFloat64(s.PacketsPerSecondOUT), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
-		ch <- prometheus.MustNewConstMetric(c.SensorBPSIn, prometheus.GaugeValue, bitsToBytes(stringToFloat64(s.BitsPerSecondIN)), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
-		ch <- prometheus.MustNewConstMetric(c.SensorBPSOut, prometheus.GaugeValue, bitsToBytes(stringToFloat64(s.BitsPerSecondOUT)), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
-		ch <- prometheus.MustNewConstMetric(c.SensorDroppedIn, prometheus.GaugeValue, stringToFloat64(s.DroppedIN), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
-		ch <- prometheus.MustNewConstMetric(c.SensorDroppedOut, prometheus.GaugeValue, stringToFloat64(s.DroppedOUT), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
-		ch <- prometheus.MustNewConstMetric(c.SensorUsageIn, prometheus.GaugeValue, stringToFloat64(s.UsageIN), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
-		ch <- prometheus.MustNewConstMetric(c.SensorUsageOut, prometheus.GaugeValue, stringToFloat64(s.UsageOUT), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
-		ch <- prometheus.MustNewConstMetric(c.SensorLoad, prometheus.GaugeValue, stringToFloat64(s.Load), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
-		ch <- prometheus.MustNewConstMetric(c.SensorCpu, prometheus.GaugeValue, stringToFloat64(s.Cpu), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
-		ch <- prometheus.MustNewConstMetric(c.SensorRam, prometheus.GaugeValue, float64(s.Ram), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
+		ch <- prometheus.MustNewConstMetric(c.SensorInternalIPS, prometheus.GaugeValue, s.InternalIPs.Float64(), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
+		ch <- prometheus.MustNewConstMetric(c.SensorExternalIPS, prometheus.GaugeValue, s.ExternalIPs.Float64(), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
+		ch <- prometheus.MustNewConstMetric(c.SensorPPSIn, prometheus.GaugeValue, s.PacketsPerSecondIn.Float64(), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
+		ch <- prometheus.MustNewConstMetric(c.SensorPPSOut, prometheus.GaugeValue, s.PacketsPerSecondOut.Float64(), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
+		ch <- prometheus.MustNewConstMetric(c.SensorBPSIn, prometheus.GaugeValue, bitsToBytes(s.BitsPerSecondIn.Float64()), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
+		ch <- prometheus.MustNewConstMetric(c.SensorBPSOut, prometheus.GaugeValue, bitsToBytes(s.BitsPerSecondOut.Float64()), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
+		ch <- prometheus.MustNewConstMetric(c.SensorDroppedIn, prometheus.GaugeValue, s.DroppedIn.Float64(), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
+		ch <- prometheus.MustNewConstMetric(c.SensorDroppedOut, prometheus.GaugeValue, s.DroppedOut.Float64(), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
+		ch <- prometheus.MustNewConstMetric(c.SensorUsageIn, prometheus.GaugeValue, s.UsageIn.Float64(), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
+		ch <- prometheus.MustNewConstMetric(c.SensorUsageOut, prometheus.GaugeValue, s.UsageOut.Float64(), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
+		ch <- prometheus.MustNewConstMetric(c.SensorLoad, prometheus.GaugeValue, s.Load.Float64(), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
+		ch <- prometheus.MustNewConstMetric(c.SensorCpu, prometheus.GaugeValue, s.CPU.Float64(), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
+		ch <- prometheus.MustNewConstMetric(c.SensorRam, prometheus.GaugeValue, s.RAM.Float64(), s.Sensor.InterfaceName, s.Sensor.InterfaceID)
 	}
+
+	return err
 }
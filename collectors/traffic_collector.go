@@ -5,15 +5,16 @@ import (
 	"github.com/tomvil/countries"
 	ipprotocols "github.com/tomvil/go-ipprotocols"
 
-	"strconv"
+	"context"
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
 	wgc "github.com/tomvil/wanguard_exporter/client"
+	"github.com/tomvil/wanguard_exporter/client/wgapi"
 )
 
 type TrafficCollector struct {
-	wgClient            *wgc.Client
+	api                 *wgapi.Service
 	CountryTopPPSIn     *prometheus.Desc
 	CountryTopPPSOut    *prometheus.Desc
 	CountryTopBPSIn     *prometheus.Desc
@@ -32,46 +33,10 @@ type TrafficCollector struct {
 	TalkersTopBPSOut    *prometheus.Desc
 }
 
-type CountryTop struct {
-	Top map[string]Country
-}
-
-type Country struct {
-	Country string
-	Value   int
-}
-
-type IPVersionTop struct {
-	Top map[string]IPVersion
-}
-
-type IPVersion struct {
-	IPVersion string `json:"Description"`
-	Value     int
-}
-
-type IPProtocolTop struct {
-	Top map[string]IPProtocol
-}
-
-type IPProtocol struct {
-	IPProtocol int `json:"ip_protocol"`
-	Value      int
-}
-
-type TalkerTop struct {
-	Top map[string]Talker
-}
-
-type Talker struct {
-	IPAddress string `json:"ip_address"`
-	Value     int
-}
-
-func NewTrafficCollector(wgclient *wgc.Client) *TrafficCollector {
+func NewTrafficCollector(wgclient wgc.API) *TrafficCollector {
 	prefix := "wanguard_traffic"
 	return &TrafficCollector{
-		wgClient:            wgclient,
+		api:                 wgapi.New(wgclient),
 		CountryTopPPSIn:     prometheus.NewDesc(prefix+"country_packets_per_second_in", "Packets per second in by country", []string{"country", "country_code"}, nil),
 		CountryTopPPSOut:    prometheus.NewDesc(prefix+"country_packets_per_second_out", "Packets per second out by country", []string{"country", "country_code"}, nil),
 		CountryTopBPSIn:     prometheus.NewDesc(prefix+"country_bytes_per_second_in", "bytes per second in by country", []string{"country", "country_code"}, nil),
@@ -111,105 +76,83 @@ func (c *TrafficCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (c *TrafficCollector) Collect(ch chan<- prometheus.Metric) {
+	collect(c, ch)
+}
+
+func (c *TrafficCollector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) error {
 	var wsync sync.WaitGroup
+	var errs errorList
 	wsync.Add(16)
 
-	go collectTopTrafficByCountry(c.CountryTopPPSIn, ch, c.wgClient, &wsync, "Packets", "Inbound")
-	go collectTopTrafficByCountry(c.CountryTopBPSIn, ch, c.wgClient, &wsync, "Bits", "Inbound")
-	go collectTopTrafficByCountry(c.CountryTopPPSOut, ch, c.wgClient, &wsync, "Packets", "Outbound")
-	go collectTopTrafficByCountry(c.CountryTopBPSOut, ch, c.wgClient, &wsync, "Bits", "Outbound")
+	go collectTopTrafficByCountry(ctx, c.CountryTopPPSIn, ch, c.api, &wsync, &errs, wgapi.Packets, wgapi.Inbound)
+	go collectTopTrafficByCountry(ctx, c.CountryTopBPSIn, ch, c.api, &wsync, &errs, wgapi.Bits, wgapi.Inbound)
+	go collectTopTrafficByCountry(ctx, c.CountryTopPPSOut, ch, c.api, &wsync, &errs, wgapi.Packets, wgapi.Outbound)
+	go collectTopTrafficByCountry(ctx, c.CountryTopBPSOut, ch, c.api, &wsync, &errs, wgapi.Bits, wgapi.Outbound)
 
-	go collectTopTrafficByIPVersion(c.IPVersionTopPPSIn, ch, c.wgClient, &wsync, "Packets", "Inbound")
-	go collectTopTrafficByIPVersion(c.IPVersionTopBPSIn, ch, c.wgClient, &wsync, "Bits", "Inbound")
-	go collectTopTrafficByIPVersion(c.IPVersionTopPPSOut, ch, c.wgClient, &wsync, "Packets", "Outbound")
-	go collectTopTrafficByIPVersion(c.IPVersionTopBPSOut, ch, c.wgClient, &wsync, "Bits", "Outbound")
+	go collectTopTrafficByIPVersion(ctx, c.IPVersionTopPPSIn, ch, c.api, &wsync, &errs, wgapi.Packets, wgapi.Inbound)
+	go collectTopTrafficByIPVersion(ctx, c.IPVersionTopBPSIn, ch, c.api, &wsync, &errs, wgapi.Bits, wgapi.Inbound)
+	go collectTopTrafficByIPVersion(ctx, c.IPVersionTopPPSOut, ch, c.api, &wsync, &errs, wgapi.Packets, wgapi.Outbound)
+	go collectTopTrafficByIPVersion(ctx, c.IPVersionTopBPSOut, ch, c.api, &wsync, &errs, wgapi.Bits, wgapi.Outbound)
 
-	go collectTopTrafficByIPProtocol(c.IPProtocolTopPPSIn, ch, c.wgClient, &wsync, "Packets", "Inbound")
-	go collectTopTrafficByIPProtocol(c.IPProtocolTopBPSIn, ch, c.wgClient, &wsync, "Bits", "Inbound")
-	go collectTopTrafficByIPProtocol(c.IPProtocolTopPPSOut, ch, c.wgClient, &wsync, "Packets", "Outbound")
-	go collectTopTrafficByIPProtocol(c.IPProtocolTopBPSOut, ch, c.wgClient, &wsync, "Bits", "Outbound")
+	go collectTopTrafficByIPProtocol(ctx, c.IPProtocolTopPPSIn, ch, c.api, &wsync, &errs, wgapi.Packets, wgapi.Inbound)
+	go collectTopTrafficByIPProtocol(ctx, c.IPProtocolTopBPSIn, ch, c.api, &wsync, &errs, wgapi.Bits, wgapi.Inbound)
+	go collectTopTrafficByIPProtocol(ctx, c.IPProtocolTopPPSOut, ch, c.api, &wsync, &errs, wgapi.Packets, wgapi.Outbound)
+	go collectTopTrafficByIPProtocol(ctx, c.IPProtocolTopBPSOut, ch, c.api, &wsync, &errs, wgapi.Bits, wgapi.Outbound)
 
-	go collectTopTrafficByTalkers(c.TalkersTopPPSIn, ch, c.wgClient, &wsync, "Packets", "Inbound")
-	go collectTopTrafficByTalkers(c.TalkersTopBPSIn, ch, c.wgClient, &wsync, "Bits", "Inbound")
-	go collectTopTrafficByTalkers(c.TalkersTopPPSOut, ch, c.wgClient, &wsync, "Packets", "Outbound")
-	go collectTopTrafficByTalkers(c.TalkersTopBPSOut, ch, c.wgClient, &wsync, "Bits", "Outbound")
+	go collectTopTrafficByTalkers(ctx, c.TalkersTopPPSIn, ch, c.api, &wsync, &errs, wgapi.Packets, wgapi.Inbound)
+	go collectTopTrafficByTalkers(ctx, c.TalkersTopBPSIn, ch, c.api, &wsync, &errs, wgapi.Bits, wgapi.Inbound)
+	go collectTopTrafficByTalkers(ctx, c.TalkersTopPPSOut, ch, c.api, &wsync, &errs, wgapi.Packets, wgapi.Outbound)
+	go collectTopTrafficByTalkers(ctx, c.TalkersTopBPSOut, ch, c.api, &wsync, &errs, wgapi.Bits, wgapi.Outbound)
 
 	wsync.Wait()
 
+	return errs.err()
 }
 
-func collectTopTrafficByCountry(desc *prometheus.Desc, ch chan<- prometheus.Metric, wgclient *wgc.Client, wsync *sync.WaitGroup, unit string, direction string) {
-	var countryTop CountryTop
-
-	href := "sensor_live_tops?top_type=Countries" + "&unit=" + unit + "&direction=" + direction
+func collectTopTrafficByCountry(ctx context.Context, desc *prometheus.Desc, ch chan<- prometheus.Metric, api *wgapi.Service, wsync *sync.WaitGroup, errs *errorList, unit wgapi.Unit, direction wgapi.Direction) {
+	defer wsync.Done()
 
-	err := wgclient.GetParsed(href, &countryTop)
-	if err != nil {
-		logging.Error("Error: %v", err)
-	}
+	countryTop, err := api.TopCountries(ctx, unit, direction)
+	errs.add(err)
 
-	for i := 1; i <= len(countryTop.Top); i++ {
-		k := strconv.Itoa(i)
-		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(countryTop.Top[k].Value), countryTop.Top[k].Country, countries.ByName(countryTop.Top[k].Country).Alpha2())
+	for _, top := range countryTop {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, top.Value.Float64(), top.Country, countries.ByName(top.Country).Alpha2())
 	}
-
-	defer wsync.Done()
 }
 
-func collectTopTrafficByIPVersion(desc *prometheus.Desc, ch chan<- prometheus.Metric, wgclient *wgc.Client, wsync *sync.WaitGroup, unit string, direction string) {
-	var ipVersionTop IPVersionTop
-
-	href := "sensor_live_tops?top_type=IP%20Versions" + "&unit=" + unit + "&direction=" + direction
+func collectTopTrafficByIPVersion(ctx context.Context, desc *prometheus.Desc, ch chan<- prometheus.Metric, api *wgapi.Service, wsync *sync.WaitGroup, errs *errorList, unit wgapi.Unit, direction wgapi.Direction) {
+	defer wsync.Done()
 
-	err := wgclient.GetParsed(href, &ipVersionTop)
-	if err != nil {
-		logging.Error("Error: %v", err)
-	}
+	ipVersionTop, err := api.TopIPVersions(ctx, unit, direction)
+	errs.add(err)
 
-	for i := 1; i <= len(ipVersionTop.Top); i++ {
-		k := strconv.Itoa(i)
-		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(ipVersionTop.Top[k].Value), ipVersionTop.Top[k].IPVersion)
+	for _, top := range ipVersionTop {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, top.Value.Float64(), top.Description)
 	}
-
-	defer wsync.Done()
 }
 
-func collectTopTrafficByIPProtocol(desc *prometheus.Desc, ch chan<- prometheus.Metric, wgclient *wgc.Client, wsync *sync.WaitGroup, unit string, direction string) {
-	var ipProtocolTop IPProtocolTop
-
-	href := "sensor_live_tops?top_type=IP%20Protocols" + "&unit=" + unit + "&direction=" + direction
+func collectTopTrafficByIPProtocol(ctx context.Context, desc *prometheus.Desc, ch chan<- prometheus.Metric, api *wgapi.Service, wsync *sync.WaitGroup, errs *errorList, unit wgapi.Unit, direction wgapi.Direction) {
+	defer wsync.Done()
 
-	err := wgclient.GetParsed(href, &ipProtocolTop)
-	if err != nil {
-		logging.Error("Error: %v", err)
-	}
+	ipProtocolTop, err := api.TopIPProtocols(ctx, unit, direction)
+	errs.add(err)
 
-	for i := 1; i <= len(ipProtocolTop.Top); i++ {
-		k := strconv.Itoa(i)
-		protocolName, err := ipprotocols.GetProtocolName(ipProtocolTop.Top[k].IPProtocol)
+	for _, top := range ipProtocolTop {
+		protocolName, err := ipprotocols.GetProtocolName(top.IPProtocol)
 		if err != nil {
-			logging.Error("failed to get protocol name for protocol number: %v", ipProtocolTop.Top[k].IPProtocol)
+			logging.Error("failed to get protocol name for protocol number: %v", top.IPProtocol)
 		}
-		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(ipProtocolTop.Top[k].Value), protocolName)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, top.Value.Float64(), protocolName)
 	}
-
-	defer wsync.Done()
 }
 
-func collectTopTrafficByTalkers(desc *prometheus.Desc, ch chan<- prometheus.Metric, wgclient *wgc.Client, wsync *sync.WaitGroup, unit string, direction string) {
-	var talkerTop TalkerTop
-
-	href := "sensor_live_tops?top_type=Talkers" + "&unit=" + unit + "&direction=" + direction
+func collectTopTrafficByTalkers(ctx context.Context, desc *prometheus.Desc, ch chan<- prometheus.Metric, api *wgapi.Service, wsync *sync.WaitGroup, errs *errorList, unit wgapi.Unit, direction wgapi.Direction) {
+	defer wsync.Done()
 
-	err := wgclient.GetParsed(href, &talkerTop)
-	if err != nil {
-		logging.Error("Error: %v", err)
-	}
+	talkerTop, err := api.TopTalkers(ctx, unit, direction)
+	errs.add(err)
 
-	for i := 1; i <= len(talkerTop.Top); i++ {
-		k := strconv.Itoa(i)
-		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(talkerTop.Top[k].Value), talkerTop.Top[k].IPAddress)
+	for _, top := range talkerTop {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, top.Value.Float64(), top.IPAddress)
 	}
-
-	defer wsync.Done()
 }
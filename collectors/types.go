@@ -1,5 +0,0 @@
-package collectors
-
-type Time struct {
-	Time string `json:"iso_8601"`
-}
@@ -0,0 +1,259 @@
+// Package config loads the wanguard_exporter configuration file
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the content of the configuration file given with -config.file. Flags given
+// on the command line override the corresponding settings of the file.
+type Config struct {
+	// API is the connection to the console scraped by /metrics
+	API APIConfig `yaml:"api"`
+
+	// Collectors holds the options of the collectors by name, e.g. "license"
+	Collectors map[string]CollectorConfig `yaml:"collectors"`
+
+	// Modules are the named settings used by /probe to reach a console
+	Modules map[string]Module `yaml:"modules"`
+}
+
+// APIConfig holds the connection settings of the console scraped by /metrics
+type APIConfig struct {
+	Address      string `yaml:"address"`
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	UsernameFile string `yaml:"username_file"`
+	PasswordFile string `yaml:"password_file"`
+	Insecure     *bool  `yaml:"insecure"`
+	ProxyURL     string `yaml:"proxy_url"`
+
+	TLS TLSConfig `yaml:"tls_config"`
+
+	// CacheTTL enables the API response cache for the listed endpoints, first match wins
+	CacheTTL []CacheTTL `yaml:"cache_ttl"`
+
+	// Compatibility adapts endpoints to the console version, first match wins
+	Compatibility []Compatibility `yaml:"compatibility"`
+}
+
+// CacheTTL is the time responses of the endpoints matching Endpoint are cached for
+type CacheTTL struct {
+	Endpoint string        `yaml:"endpoint"`
+	TTL      time.Duration `yaml:"ttl"`
+}
+
+// Compatibility adapts the endpoints matching Endpoint on the WANGuard versions from Since
+// (inclusive) to Until (exclusive), e.g. "8.2" or "8.3-21". An empty version leaves that
+// side unbounded.
+type Compatibility struct {
+	Endpoint    string            `yaml:"endpoint"`
+	Since       string            `yaml:"since"`
+	Until       string            `yaml:"until"`
+	Unsupported bool              `yaml:"unsupported"`
+	Resource    string            `yaml:"resource"`
+	Fields      map[string]string `yaml:"fields"`
+}
+
+// CollectorConfig holds the options of a collector
+type CollectorConfig struct {
+	// Enabled overrides the default of the collector.* flag
+	Enabled *bool `yaml:"enabled"`
+
+	// Keep and Drop filter the series of the collector by label value. They map label
+	// names to regular expressions matching the whole value. A series is exported if it
+	// matches all Keep expressions and none of the Drop expressions; filters on labels
+	// a series does not have are ignored.
+	Keep map[string]string `yaml:"keep"`
+	Drop map[string]string `yaml:"drop"`
+
+	// PollInterval overrides collector.poll-interval, StaleAfter collector.stale-after
+	// and Timeout collector.timeout
+	PollInterval *time.Duration `yaml:"poll_interval"`
+	StaleAfter   *time.Duration `yaml:"stale_after"`
+	Timeout      *time.Duration `yaml:"timeout"`
+}
+
+// Module holds the credentials and connection settings used to probe a group of consoles
+type Module struct {
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	UsernameFile string `yaml:"username_file"`
+	PasswordFile string `yaml:"password_file"`
+	Insecure     bool   `yaml:"insecure"`
+	ProxyURL     string `yaml:"proxy_url"`
+
+	TLS TLSConfig `yaml:"tls_config"`
+
+	// Targets are the consoles the module's credentials may be sent to, as
+	// shell patterns matched against the host (host or host:port) of the target
+	Targets []string `yaml:"targets"`
+
+	// Collectors lists the collectors run by the probe; empty means the collectors
+	// enabled with the collector.* flags
+	Collectors []string `yaml:"collectors"`
+}
+
+// TLSConfig holds the TLS settings used to verify and authenticate against a console
+type TLSConfig struct {
+	CAFile       string   `yaml:"ca_file"`
+	CertFile     string   `yaml:"cert_file"`
+	KeyFile      string   `yaml:"key_file"`
+	ServerName   string   `yaml:"server_name"`
+	PinnedSHA256 []string `yaml:"pin_sha256"`
+}
+
+// Load reads and validates the configuration file. Unknown keys are rejected so
+// that typos do not silently fall back to defaults.
+func Load(filename string) (*Config, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filename, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", filename, err)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the configuration for errors that would otherwise only show up when scraping
+func (c *Config) Validate() error {
+	for i, ttl := range c.API.CacheTTL {
+		if ttl.Endpoint == "" {
+			return fmt.Errorf("api: cache_ttl entry %d has no endpoint", i)
+		}
+		if ttl.TTL < 0 {
+			return fmt.Errorf("api: negative cache_ttl for %s", ttl.Endpoint)
+		}
+	}
+	for i, rule := range c.API.Compatibility {
+		if rule.Endpoint == "" {
+			return fmt.Errorf("api: compatibility entry %d has no endpoint", i)
+		}
+	}
+
+	for name, collector := range c.Collectors {
+		if err := collector.validate(); err != nil {
+			return fmt.Errorf("collector %q: %w", name, err)
+		}
+	}
+
+	for _, name := range c.ModuleNames() {
+		if err := c.Modules[name].validate(); err != nil {
+			return fmt.Errorf("module %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ModuleNames returns the names of the configured modules in sorted order
+func (c *Config) ModuleNames() []string {
+	names := make([]string, 0, len(c.Modules))
+	for name := range c.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c CollectorConfig) validate() error {
+	if c.PollInterval != nil && *c.PollInterval < 0 {
+		return errors.New("negative poll_interval")
+	}
+	if c.StaleAfter != nil && *c.StaleAfter < 0 {
+		return errors.New("negative stale_after")
+	}
+	if c.Timeout != nil && *c.Timeout < 0 {
+		return errors.New("negative timeout")
+	}
+	for _, filters := range []map[string]string{c.Keep, c.Drop} {
+		for label, expr := range filters {
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("invalid filter for label %s: %w", label, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (m Module) validate() error {
+	if m.Username == "" && m.UsernameFile == "" {
+		return errors.New("username or username_file is required")
+	}
+	if m.Password == "" && m.PasswordFile == "" {
+		return errors.New("password or password_file is required")
+	}
+
+	// Without an allowlist any /probe caller could have the credentials sent to a host of their choice
+	if len(m.Targets) == 0 {
+		return errors.New("at least one entry in targets is required")
+	}
+	for _, pattern := range m.Targets {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("invalid target pattern %q", pattern)
+		}
+	}
+
+	return nil
+}
+
+// AllowsTarget reports whether the module may be used to probe target, which
+// must have been normalized with ParseTarget
+func (m Module) AllowsTarget(target *url.URL) bool {
+	for _, pattern := range m.Targets {
+		if matched, _ := path.Match(pattern, target.Host); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, target.Hostname()); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTarget parses the target of a probe. Targets without a scheme default to https,
+// and credentials, queries and fragments are rejected since they come from the module.
+func ParseTarget(target string) (*url.URL, error) {
+	if target == "" {
+		return nil, errors.New("target is empty")
+	}
+	if !strings.Contains(target, "://") {
+		target = "https://" + target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target: %w", err)
+	}
+
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return nil, fmt.Errorf("unsupported target scheme %q", u.Scheme)
+	case u.Host == "":
+		return nil, errors.New("target has no host")
+	case u.User != nil:
+		return nil, errors.New("target must not contain credentials")
+	case u.RawQuery != "" || u.Fragment != "":
+		return nil, errors.New("target must not contain a query or fragment")
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	return u, nil
+}
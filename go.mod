@@ -4,8 +4,11 @@ go 1.21.5
 
 require (
 	github.com/prometheus/client_golang v1.11.1
+	github.com/prometheus/client_model v0.2.0
 	github.com/tomvil/countries v0.0.0-20220104165753-f0d74c0c9799
 	github.com/tomvil/go-ipprotocols v0.0.3
+	golang.org/x/crypto v0.31.0
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
@@ -13,9 +16,8 @@ require (
 	github.com/cespare/xxhash/v2 v2.1.1 // indirect
 	github.com/golang/protobuf v1.4.3 // indirect
 	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
-	github.com/prometheus/client_model v0.2.0 // indirect
 	github.com/prometheus/common v0.26.0 // indirect
 	github.com/prometheus/procfs v0.6.0 // indirect
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+	golang.org/x/sys v0.28.0 // indirect
 	google.golang.org/protobuf v1.26.0-rc.1 // indirect
 )
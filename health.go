@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/tomvil/wanguard_exporter/logging"
+)
+
+// readyProbeTimeout bounds the API request made by /-/ready when the last successful
+// contact is older than -web.ready-window
+const readyProbeTimeout = 5 * time.Second
+
+// readiness is the JSON body of /-/ready
+type readiness struct {
+	Ready                 bool     `json:"ready"`
+	APIAddress            string   `json:"api_address"`
+	WANGuardVersion       string   `json:"wanguard_version,omitempty"`
+	Window                string   `json:"window"`
+	LastSuccess           string   `json:"last_success,omitempty"`
+	LastSuccessAgeSeconds *float64 `json:"last_success_age_seconds,omitempty"`
+	Probed                bool     `json:"probed"`
+	Error                 string   `json:"error,omitempty"`
+}
+
+// healthyHandler reports that the process is alive, without contacting the API
+func healthyHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := w.Write([]byte("WANGuard Exporter is Healthy.\n")); err != nil {
+		logging.Error("Write error: %v", err)
+	}
+}
+
+// readyHandler reports whether the WANGuard API was successfully contacted within
+// -web.ready-window. Scrapes usually keep the last contact recent; otherwise the API
+// is probed with a cheap request before answering.
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	state := acquireState()
+	defer state.release()
+
+	client := state.client
+	status := readiness{
+		APIAddress: client.GetSanitizedTarget(),
+		Window:     readyWindow.String(),
+	}
+
+	if time.Since(client.LastSuccess()) > *readyWindow {
+		status.Probed = true
+		ctx, cancel := context.WithTimeout(r.Context(), readyProbeTimeout)
+		if err := client.Ping(ctx); err != nil {
+			status.Error = err.Error()
+		}
+		cancel()
+	}
+
+	if last := client.LastSuccess(); !last.IsZero() {
+		age := time.Since(last).Seconds()
+		status.LastSuccess = last.UTC().Format(time.RFC3339)
+		status.LastSuccessAgeSeconds = &age
+		status.Ready = time.Since(last) <= *readyWindow
+	}
+	if version, known := client.Version(); known {
+		status.WANGuardVersion = version.String()
+	}
+
+	code := http.StatusOK
+	if !status.Ready {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		logging.Error("Write error: %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	wgc "github.com/tomvil/wanguard_exporter/client"
+	"github.com/tomvil/wanguard_exporter/collectors"
+	"github.com/tomvil/wanguard_exporter/config"
+	"github.com/tomvil/wanguard_exporter/logging"
+)
+
+const (
+	// defaultProbeModule is used when a probe does not name a module
+	defaultProbeModule = "default"
+
+	// maxProbeClients bounds the number of cached probe clients; the least recently
+	// used one is dropped when a new target+module pair would exceed it
+	maxProbeClients = 256
+)
+
+// probeClients caches the API clients and collectors used by /probe, one per module and target,
+// so that consecutive probes share connections, caches and circuit breakers
+type probeClients struct {
+	cfg           *config.Config
+	options       []wgc.Option
+	newCollectors func(api wgc.API) []collectorsList
+
+	mu      sync.Mutex
+	clients map[string]*probeClient
+}
+
+type probeClient struct {
+	module     config.Module
+	client     *wgc.Client
+	collectors []collectorsList
+	lastUsed   time.Time
+}
+
+func newProbeClients(cfg *config.Config, options []wgc.Option, newCollectors func(api wgc.API) []collectorsList) *probeClients {
+	return &probeClients{
+		cfg:           cfg,
+		options:       options,
+		newCollectors: newCollectors,
+		clients:       make(map[string]*probeClient),
+	}
+}
+
+// get returns the client for target using module, creating it on first use
+func (p *probeClients) get(name string, module config.Module, target *url.URL) (*probeClient, error) {
+	key := name + "\x00" + target.String()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pc, ok := p.clients[key]; ok && reflect.DeepEqual(pc.module, module) {
+		pc.lastUsed = time.Now()
+		return pc, nil
+	}
+
+	client, err := wgc.NewClient(target.String(), module.Username, module.Password, module.Insecure,
+		append([]wgc.Option{
+			wgc.WithCredentialFiles(module.UsernameFile, module.PasswordFile),
+			wgc.WithProxy(module.ProxyURL),
+			wgc.WithTLS(wgc.TLSConfig{
+				CAFile:       module.TLS.CAFile,
+				CertFile:     module.TLS.CertFile,
+				KeyFile:      module.TLS.KeyFile,
+				ServerName:   module.TLS.ServerName,
+				PinnedSHA256: module.TLS.PinnedSHA256,
+			}),
+		}, p.options...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if old, exists := p.clients[key]; exists {
+		// The module changed, which also stops the version detection of the old client
+		old.client.Close()
+	} else if len(p.clients) >= maxProbeClients {
+		p.evictOldest()
+	}
+	client.StartVersionDetection()
+
+	pc := &probeClient{
+		module:     module,
+		client:     client,
+		collectors: p.newCollectors(client),
+		lastUsed:   time.Now(),
+	}
+	p.clients[key] = pc
+
+	return pc, nil
+}
+
+// evictOldest drops the least recently used client, p.mu must be held
+func (p *probeClients) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, pc := range p.clients {
+		if oldestKey == "" || pc.lastUsed.Before(oldest) {
+			oldestKey, oldest = key, pc.lastUsed
+		}
+	}
+	p.clients[oldestKey].client.Close()
+	delete(p.clients, oldestKey)
+}
+
+// close releases all clients
+func (p *probeClients) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, pc := range p.clients {
+		pc.client.Close()
+		delete(p.clients, key)
+	}
+}
+
+// enabled reports whether the probe runs the named collector
+func (pc *probeClient) enabled(c collectorsList) bool {
+	if len(pc.module.Collectors) == 0 {
+		return c.enabled
+	}
+	for _, name := range pc.module.Collectors {
+		if name == c.name {
+			return true
+		}
+	}
+	return false
+}
+
+// probeHandler runs the collectors against the console given in the target parameter,
+// using the credentials and settings of the module given in the module parameter
+func probeHandler(w http.ResponseWriter, r *http.Request) {
+	state := acquireState()
+	defer state.release()
+
+	probes := state.probes
+	if probes == nil {
+		http.Error(w, "No modules configured, probing requires -config.file", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+
+	target, err := config.ParseTarget(query.Get("target"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid target parameter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	name := query.Get("module")
+	if name == "" {
+		name = defaultProbeModule
+	}
+	module, ok := probes.cfg.Modules[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown module %q", name), http.StatusBadRequest)
+		return
+	}
+
+	if !module.AllowsTarget(target) {
+		logging.Warn("Rejected probe of %s: target not allowed for module %q", target.Host, name)
+		http.Error(w, fmt.Sprintf("Target %s is not allowed for module %q", target.Host, name), http.StatusForbidden)
+		return
+	}
+
+	pc, err := probes.get(name, module, target)
+	if err != nil {
+		logging.Error("Failed to create WANGuard API client for %s (module %q): %v", target.Host, name, err)
+		http.Error(w, fmt.Sprintf("Failed to create WANGuard API client: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	selected, err := selectCollectors(pc.collectors, pc.enabled, query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := scrapeContext(r)
+	defer cancel()
+	start := time.Now()
+
+	var collectErr error
+	probeRegistry := prometheus.NewRegistry()
+	probeRegistry.MustRegister(collectors.WithContextResult(ctx, state.scheduler(selected), func(err error) {
+		collectErr = err
+	}))
+
+	// Gathered after the collectors, so both describe the probe that just ran
+	resultRegistry := prometheus.NewRegistry()
+	resultRegistry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "wanguard_probe_success",
+			Help: "Whether the WANGuard API of the target answered the probe successfully (1 = success, 0 = failure)",
+		}, func() float64 {
+			// Without collectors, the API is checked with a request of the probe's own
+			if len(selected) == 0 {
+				collectErr = pc.client.Ping(ctx)
+			}
+			if collectErr != nil {
+				logging.Debug("Probe of %s failed: %v", target.Host, collectErr)
+				return 0
+			}
+			return 1
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "wanguard_probe_duration_seconds",
+			Help: "Duration of the probe in seconds",
+		}, func() float64 {
+			return time.Since(start).Seconds()
+		}),
+	)
+
+	promhttp.HandlerFor(prometheus.Gatherers{probeRegistry, resultRegistry}, promhttp.HandlerOpts{
+		ErrorLog:      nil,
+		ErrorHandling: promhttp.ContinueOnError}).ServeHTTP(w, r)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tomvil/wanguard_exporter/config"
+)
+
+const licensePayload = `{
+  "software_version": "8.3-21",
+  "licensed_sensors": 1,
+  "licensed_sensors_used": 1,
+  "licensed_sensors_remaining": 0,
+  "licensed_sensor_interfaces": "∞",
+  "licensed_dpdk_engines": 0,
+  "licensed_dpdk_engines_used": 0,
+  "licensed_dpdk_engines_remaining": 0,
+  "licensed_filters": 1,
+  "licensed_filters_used": 1,
+  "licensed_filters_remaining": 0,
+  "licensed_on": "2024-10-24 12:15:29",
+  "license_expiry_date_remaining": "1 days",
+  "support_expiry_date_remaining": "1 days"
+}`
+
+// useProbeState makes a state probing the console at consoleURL the current one
+func useProbeState(t *testing.T, consoleURL string) {
+	t.Setenv("WANGUARD_PASSWORD", "p")
+	console, err := url.Parse(consoleURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := buildState(&config.Config{Modules: map[string]config.Module{
+		"default": {Username: "u", Password: "p", Targets: []string{console.Host}},
+	}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	previous := currentState.Load()
+	currentState.Store(state)
+	t.Cleanup(func() {
+		currentState.Store(previous)
+		state.close()
+	})
+}
+
+func TestProbeSuccess(t *testing.T) {
+	// Only the license collector gets an answer
+	console := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/license_manager") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(licensePayload)); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer console.Close()
+	useProbeState(t, console.URL)
+
+	tests := []struct {
+		query   string
+		success string
+	}{
+		{"collect[]=license", "wanguard_probe_success 1"},
+		{"collect[]=license&collect[]=sensors", "wanguard_probe_success 0"},
+		{"collect[]=sensors&collect[]=license", "wanguard_probe_success 0"},
+		{"collect[]=license", "wanguard_probe_success 1"},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		probeHandler(w, httptest.NewRequest(http.MethodGet, "/probe?target="+url.QueryEscape(console.URL)+"&"+test.query, nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", test.query, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), "\n"+test.success+"\n") {
+			t.Errorf("%s: expected %q in\n%s", test.query, test.success, w.Body.String())
+		}
+	}
+}
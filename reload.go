@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tomvil/wanguard_exporter/logging"
+)
+
+var (
+	configReloadSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "wanguard_exporter_config_last_reload_successful",
+		Help: "Whether the last configuration reload attempt was successful (1 = success, 0 = failure)",
+	})
+
+	configReloadTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "wanguard_exporter_config_last_reload_success_timestamp_seconds",
+		Help: "Timestamp of the last successful configuration reload",
+	})
+
+	// reloadMu serializes reloads triggered by signals and HTTP requests
+	reloadMu sync.Mutex
+)
+
+// reload loads the configuration file again and atomically replaces the current state.
+// The previous state stays in use if the new configuration is invalid.
+func reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	cfg, err := loadConfig()
+	if err == nil {
+		var s *exporterState
+		if s, err = buildState(cfg); err == nil {
+			old := currentState.Swap(s)
+			go old.close()
+			s.client.StartVersionDetection()
+		}
+	}
+
+	if err != nil {
+		configReloadSuccess.Set(0)
+		logging.Error("Failed to reload configuration: %v", err)
+		return err
+	}
+
+	configReloadSuccess.Set(1)
+	configReloadTimestamp.SetToCurrentTime()
+	logging.Info("Configuration reloaded")
+
+	return nil
+}
+
+// reloadOnSIGHUP reloads the configuration whenever the process receives SIGHUP
+func reloadOnSIGHUP() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		_ = reload()
+	}
+}
+
+// reloadHandler reloads the configuration on POST requests
+func reloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Only POST requests allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := reload(); err != nil {
+		http.Error(w, "Failed to reload configuration: "+err.Error(), http.StatusInternalServerError)
+	}
+}
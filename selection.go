@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// selectCollectors returns the collectors a scrape runs: the ones enabled reports, narrowed
+// to the names in the collect[] query parameter if given, minus the names in exclude[].
+// Unknown names and collect[] names of disabled collectors are rejected, so that a typo in
+// a scrape config fails loudly instead of returning fewer series.
+func selectCollectors(list []collectorsList, enabled func(c collectorsList) bool, query url.Values) ([]collectorsList, error) {
+	byName := make(map[string]collectorsList, len(list))
+	for _, c := range list {
+		byName[c.name] = c
+	}
+
+	collect := make(map[string]bool)
+	for _, name := range query["collect[]"] {
+		c, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown collector %q in collect[]", name)
+		}
+		if !enabled(c) {
+			return nil, fmt.Errorf("collector %q is disabled", name)
+		}
+		collect[name] = true
+	}
+
+	exclude := make(map[string]bool)
+	for _, name := range query["exclude[]"] {
+		if _, ok := byName[name]; !ok {
+			return nil, fmt.Errorf("unknown collector %q in exclude[]", name)
+		}
+		exclude[name] = true
+	}
+
+	var selected []collectorsList
+	for _, c := range list {
+		if !enabled(c) || exclude[c.name] || (len(collect) > 0 && !collect[c.name]) {
+			continue
+		}
+		selected = append(selected, c)
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestSelectCollectors(t *testing.T) {
+	list := []collectorsList{
+		{name: "license", enabled: true},
+		{name: "anomalies", enabled: true},
+		{name: "traffic", enabled: false},
+		{name: "sensors", enabled: true},
+	}
+	enabled := func(c collectorsList) bool { return c.enabled }
+
+	tests := []struct {
+		name     string
+		query    string
+		selected []string
+		wantErr  bool
+	}{
+		{"all enabled", "", []string{"license", "anomalies", "sensors"}, false},
+		{"collect", "collect[]=sensors&collect[]=license", []string{"license", "sensors"}, false},
+		{"exclude", "exclude[]=anomalies", []string{"license", "sensors"}, false},
+		{"collect and exclude", "collect[]=license&collect[]=anomalies&exclude[]=anomalies", []string{"license"}, false},
+		{"exclude everything collected", "collect[]=license&exclude[]=license", nil, false},
+		{"exclude disabled", "exclude[]=traffic", []string{"license", "anomalies", "sensors"}, false},
+		{"unknown in collect", "collect[]=licence", nil, true},
+		{"unknown in exclude", "exclude[]=licence", nil, true},
+		{"disabled in collect", "collect[]=traffic", nil, true},
+		{"disabled among enabled in collect", "collect[]=license&collect[]=traffic", nil, true},
+	}
+
+	for _, test := range tests {
+		query, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		selected, err := selectCollectors(list, enabled, query)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", test.name, err)
+			continue
+		}
+
+		var names []string
+		for _, c := range selected {
+			names = append(names, c.name)
+		}
+		if !reflect.DeepEqual(names, test.selected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.selected, names)
+		}
+	}
+}
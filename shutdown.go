@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/tomvil/wanguard_exporter/logging"
+	"github.com/tomvil/wanguard_exporter/web"
+)
+
+// serve runs server until SIGTERM or SIGINT, then stops accepting connections and drains
+// the in-flight requests within -web.shutdown-timeout before releasing the API clients.
+// A second signal during the drain terminates the process immediately.
+func serve(server *http.Server) {
+	// Requests still running at the drain deadline are cancelled through their context,
+	// which aborts their outstanding API calls
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	server.BaseContext = func(net.Listener) context.Context { return baseCtx }
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- web.ListenAndServe(server, *webConfigFile)
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+
+	select {
+	case err := <-serverErr:
+		logging.Fatal("Server error: %v", err)
+	case sig := <-stop:
+		logging.Info("Received %s, draining in-flight requests for up to %s", sig, *shutdownTimeout)
+	}
+	signal.Stop(stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logging.Warn("In-flight requests did not finish in time, cancelling them: %v", err)
+		cancelRequests()
+		server.Close()
+	}
+	if err := <-serverErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logging.Error("Server error: %v", err)
+	}
+
+	// Keep reloads from swapping in a new state while the current one is released
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	currentState.Load().close()
+
+	logging.Info("Shutdown complete")
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	wgc "github.com/tomvil/wanguard_exporter/client"
+	"github.com/tomvil/wanguard_exporter/collectors"
+	"github.com/tomvil/wanguard_exporter/config"
+)
+
+// exporterState is everything built from the flags and the configuration file. It is
+// replaced as a whole on reload, so a scrape never sees a mix of old and new settings.
+type exporterState struct {
+	client     *wgc.Client
+	collectors []collectorsList
+	pollers    []*collectors.Poller
+	probes     *probeClients
+
+	// settings of the collectors by name, also applied to the probe collectors
+	settings map[string]collectorSettings
+
+	// inUse is read-locked by the scrapes using the state, so that a replaced state
+	// is only closed once they are done with it
+	inUse sync.RWMutex
+}
+
+type collectorSettings struct {
+	enabled      bool
+	filter       *collectors.LabelFilter
+	pollInterval time.Duration
+	staleAfter   time.Duration
+	timeout      time.Duration
+}
+
+var currentState atomic.Pointer[exporterState]
+
+// acquireState returns the current state, which must be released after use
+func acquireState() *exporterState {
+	for {
+		s := currentState.Load()
+		s.inUse.RLock()
+		// A reload may have replaced and closed s before it was locked
+		if currentState.Load() == s {
+			return s
+		}
+		s.inUse.RUnlock()
+	}
+}
+
+func (s *exporterState) release() {
+	s.inUse.RUnlock()
+}
+
+// close waits for the scrapes using s, stops its pollers and releases its clients
+func (s *exporterState) close() {
+	s.inUse.Lock()
+	defer s.inUse.Unlock()
+
+	for _, p := range s.pollers {
+		p.Stop()
+	}
+	s.client.Close()
+	if s.probes != nil {
+		s.probes.close()
+	}
+}
+
+// newCollectors creates the collectors reading from api with the state's settings applied.
+// With poll, the enabled collectors with a poll interval are started in the background.
+func (s *exporterState) newCollectors(api wgc.API, poll bool) []collectorsList {
+	list := newCollectorsList(api)
+	for i := range list {
+		settings := s.settings[list[i].name]
+		list[i].enabled = settings.enabled
+		filtered := collectors.WithLabelFilter(list[i].collector, settings.filter)
+
+		if !poll || !settings.enabled || settings.pollInterval <= 0 {
+			list[i].collector = collectors.Instrument(list[i].name, filtered)
+			continue
+		}
+		p := collectors.NewPoller(list[i].name, filtered, settings.pollInterval, settings.staleAfter)
+		p.Start()
+		s.pollers = append(s.pollers, p)
+		list[i].collector = p
+	}
+	return list
+}
+
+// scheduler returns the scheduler running the selected collectors of a scrape within their timeouts
+func (s *exporterState) scheduler(selected []collectorsList) *collectors.Scheduler {
+	jobs := make([]collectors.Job, 0, len(selected))
+	for _, c := range selected {
+		jobs = append(jobs, collectors.Job{Name: c.name, Collector: c.collector, Timeout: s.settings[c.name].timeout})
+	}
+	return collectors.NewScheduler(*collectorWorkers, jobs)
+}
+
+// loadConfig loads -config.file and checks the collector names it refers to. Without
+// -config.file it returns an empty configuration, leaving everything to the flags.
+func loadConfig() (*config.Config, error) {
+	if *configFile == "" {
+		return &config.Config{}, nil
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+	for _, c := range newCollectorsList(nil) {
+		known[c.name] = true
+	}
+	for name := range cfg.Collectors {
+		if !known[name] {
+			return nil, fmt.Errorf("invalid config file %s: unknown collector %q", *configFile, name)
+		}
+	}
+	for _, name := range cfg.ModuleNames() {
+		for _, collector := range cfg.Modules[name].Collectors {
+			if !known[collector] {
+				return nil, fmt.Errorf("invalid config file %s: module %q: unknown collector %q", *configFile, name, collector)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// buildState creates the API clients and collectors for cfg. Flags given on the
+// command line take precedence over the settings of the configuration file.
+func buildState(cfg *config.Config) (*exporterState, error) {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	setting := func(name, fileValue string) string {
+		if set[name] || fileValue == "" {
+			return flag.Lookup(name).Value.String()
+		}
+		return fileValue
+	}
+
+	insecure := *apiInsecure
+	if !set["api.insecure"] && cfg.API.Insecure != nil {
+		insecure = *cfg.API.Insecure
+	}
+
+	pins := splitList(*apiTLSPins)
+	if !set["api.tls.pin-sha256"] && len(cfg.API.TLS.PinnedSHA256) > 0 {
+		pins = cfg.API.TLS.PinnedSHA256
+	}
+
+	cachePolicies, err := wgc.ParseCachePolicies(*apiCacheTTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -api.cache-ttl: %w", err)
+	}
+	if !set["api.cache-ttl"] && len(cfg.API.CacheTTL) > 0 {
+		cachePolicies = cachePolicies[:0]
+		for _, ttl := range cfg.API.CacheTTL {
+			cachePolicies = append(cachePolicies, wgc.CachePolicy{Pattern: ttl.Endpoint, TTL: ttl.TTL})
+		}
+	}
+
+	compatibility, err := compatibilityRules(cfg.API.Compatibility)
+	if err != nil {
+		return nil, err
+	}
+
+	password := setting("api.password", cfg.API.Password)
+	passwordFile := setting("api.password-file", cfg.API.PasswordFile)
+	if password == "" && passwordFile == "" {
+		password = os.Getenv("WANGUARD_PASSWORD")
+		if password == "" {
+			return nil, errors.New(`Please set to WANGuard API Password!
+		API Password can be set with api.password or api.password-file flags, in the
+		config file or by setting WANGUARD_PASSWORD environment variable.`)
+		}
+	}
+
+	apiOptions := []wgc.Option{
+		wgc.WithRetryPolicy(wgc.RetryPolicy{
+			MaxRetries: *apiRetries,
+			MinBackoff: *apiRetryMin,
+			MaxBackoff: *apiRetryMax,
+		}),
+		wgc.WithCircuitBreaker(wgc.CircuitBreakerPolicy{
+			FailureThreshold: *apiBreakerThreshold,
+			OpenTimeout:      *apiBreakerTimeout,
+		}),
+		wgc.WithCache(cachePolicies),
+		wgc.WithMaxResponseSize(*apiMaxRespSize),
+		wgc.WithPagination(wgc.Pagination{
+			PageSize: *apiPageSize,
+			MaxItems: *apiMaxItems,
+		}),
+		wgc.WithRateLimit(wgc.RateLimit{
+			MaxInFlight:       *apiMaxInFlight,
+			RequestsPerSecond: *apiRequestsPerSec,
+			Burst:             *apiRateLimitBurst,
+			MaxWait:           *apiLimiterMaxWait,
+		}),
+	}
+	if len(compatibility) > 0 {
+		apiOptions = append(apiOptions, wgc.WithCompatibility(compatibility))
+	}
+
+	wgClient, err := wgc.NewClient(setting("api.address", cfg.API.Address), setting("api.username", cfg.API.Username), password, insecure,
+		append([]wgc.Option{
+			wgc.WithCredentialFiles(setting("api.username-file", cfg.API.UsernameFile), passwordFile),
+			wgc.WithProxy(setting("api.proxy-url", cfg.API.ProxyURL)),
+			wgc.WithSSHTunnel(wgc.SSHConfig{
+				Host:           *apiSSHHost,
+				User:           *apiSSHUser,
+				KeyFile:        *apiSSHKeyFile,
+				KnownHostsFile: *apiSSHKnownHosts,
+			}),
+			wgc.WithTLS(wgc.TLSConfig{
+				CAFile:       setting("api.tls.ca-file", cfg.API.TLS.CAFile),
+				CertFile:     setting("api.tls.cert-file", cfg.API.TLS.CertFile),
+				KeyFile:      setting("api.tls.key-file", cfg.API.TLS.KeyFile),
+				ServerName:   setting("api.tls.server-name", cfg.API.TLS.ServerName),
+				PinnedSHA256: pins,
+			}),
+		}, apiOptions...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WANGuard API client: %w", err)
+	}
+
+	s := &exporterState{client: wgClient, settings: make(map[string]collectorSettings)}
+	for _, c := range newCollectorsList(nil) {
+		settings := collectorSettings{enabled: c.enabled}
+		collectorConfig := cfg.Collectors[c.name]
+		if !set["collector."+c.name] && collectorConfig.Enabled != nil {
+			settings.enabled = *collectorConfig.Enabled
+		}
+		if settings.filter, err = collectors.NewLabelFilter(collectorConfig.Keep, collectorConfig.Drop); err != nil {
+			wgClient.Close()
+			return nil, fmt.Errorf("collector %q: %w", c.name, err)
+		}
+		settings.pollInterval = *collectorPollInterval
+		if !set["collector.poll-interval"] && collectorConfig.PollInterval != nil {
+			settings.pollInterval = *collectorConfig.PollInterval
+		}
+		settings.staleAfter = *collectorStaleAfter
+		if !set["collector.stale-after"] && collectorConfig.StaleAfter != nil {
+			settings.staleAfter = *collectorConfig.StaleAfter
+		}
+		settings.timeout = *collectorTimeout
+		if !set["collector.timeout"] && collectorConfig.Timeout != nil {
+			settings.timeout = *collectorConfig.Timeout
+		}
+		s.settings[c.name] = settings
+	}
+	s.collectors = s.newCollectors(wgClient, true)
+
+	if len(cfg.Modules) > 0 {
+		// Probes always collect on request, targets are only known once probed
+		s.probes = newProbeClients(cfg, apiOptions, func(api wgc.API) []collectorsList {
+			return s.newCollectors(api, false)
+		})
+	}
+
+	return s, nil
+}
+
+// compatibilityRules converts the compatibility section of the config file
+func compatibilityRules(entries []config.Compatibility) ([]wgc.Compatibility, error) {
+	var rules []wgc.Compatibility
+	for _, entry := range entries {
+		rule := wgc.Compatibility{
+			Endpoint:    entry.Endpoint,
+			Unsupported: entry.Unsupported,
+			Resource:    entry.Resource,
+			Fields:      entry.Fields,
+		}
+		var err error
+		if entry.Since != "" {
+			if rule.Since, err = wgc.ParseVersion(entry.Since); err != nil {
+				return nil, fmt.Errorf("compatibility rule for %s: %w", entry.Endpoint, err)
+			}
+		}
+		if entry.Until != "" {
+			if rule.Until, err = wgc.ParseVersion(entry.Until); err != nil {
+				return nil, fmt.Errorf("compatibility rule for %s: %w", entry.Endpoint, err)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// splitList splits a comma separated flag value, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
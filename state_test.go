@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAcquireStateDuringReload(t *testing.T) {
+	previous := currentState.Load()
+	t.Cleanup(func() { currentState.Store(previous) })
+
+	old, replacement := &exporterState{}, &exporterState{}
+	currentState.Store(old)
+
+	// The reload is closing old while a scrape loads it
+	old.inUse.Lock()
+	acquired := make(chan *exporterState)
+	go func() {
+		s := acquireState()
+		acquired <- s
+		s.release()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	currentState.Swap(replacement)
+	old.inUse.Unlock()
+
+	select {
+	case s := <-acquired:
+		if s != replacement {
+			t.Error("Expected the scrape to use the replacement state")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("acquireState did not return")
+	}
+}
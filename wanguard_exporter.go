@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -18,19 +22,64 @@ const (
 )
 
 type collectorsList struct {
-	enabled   *bool
-	collector prometheus.Collector
+	name      string
+	enabled   bool
+	collector collectors.ContextCollector
 }
 
 var (
-	showVersion = flag.Bool("version", false, "Print version and other information about wanguard_exporter")
-	listenAddr  = flag.String("web.listen-address", ":9868", "The address to listen on for HTTP requests")
-	metricsPath = flag.String("web.metrics-path", "/metrics", "Path under which metrics will be exposed")
+	showVersion   = flag.Bool("version", false, "Print version and other information about wanguard_exporter")
+	listenAddr    = flag.String("web.listen-address", ":9868", "The address to listen on for HTTP requests")
+	webConfigFile = flag.String("web.config.file", "", "Web configuration file enabling TLS, client certificate and basic authentication on the exporter's endpoints")
+	metricsPath   = flag.String("web.metrics-path", "/metrics", "Path under which metrics will be exposed")
+	probePath     = flag.String("web.probe-path", "/probe", "Path under which consoles configured in config.file modules are probed")
+	configFile    = flag.String("config.file", "", "YAML configuration file, reloaded on SIGHUP or POST /-/reload; flags given on the command line take precedence")
+	readyWindow   = flag.Duration("web.ready-window", 2*time.Minute, "How recently the WANGuard API must have been contacted successfully for /-/ready to report ready")
+	timeoutOffset = flag.Duration("web.timeout-offset", 500*time.Millisecond, "Offset to subtract from the Prometheus scrape timeout when bounding WANGuard API calls")
+
+	readHeaderTimeout = flag.Duration("web.read-header-timeout", 10*time.Second, "Maximum time to read the headers of a request")
+	readTimeout       = flag.Duration("web.read-timeout", 30*time.Second, "Maximum time to read a request, including its body")
+	writeTimeout      = flag.Duration("web.write-timeout", 2*time.Minute, "Maximum time to answer a request; must exceed the longest scrape timeout")
+	idleTimeout       = flag.Duration("web.idle-timeout", time.Minute, "Maximum time an idle keep-alive connection is kept open")
+	maxHeaderBytes    = flag.Int("web.max-header-bytes", 1<<16, "Maximum size in bytes of the request headers")
+	shutdownTimeout   = flag.Duration("web.shutdown-timeout", 15*time.Second, "Time in-flight requests are given to finish on SIGTERM or SIGINT before they are cancelled")
+
 	apiAddress  = flag.String("api.address", "http://127.0.0.1:81", "WANGuard API address")
 	apiUsername = flag.String("api.username", "admin", "WANGuard API username")
 	apiPassword = flag.String("api.password", "", "WANGuard API password")
 	apiInsecure = flag.Bool("api.insecure", false, "Allow HTTP for remote hosts and skip TLS certificate verification")
 
+	apiUsernameFile = flag.String("api.username-file", "", "File containing the WANGuard API username, re-read when it changes (overrides api.username)")
+	apiPasswordFile = flag.String("api.password-file", "", "File containing the WANGuard API password, re-read when it changes (overrides api.password)")
+
+	apiProxyURL = flag.String("api.proxy-url", "", "Proxy used to reach the WANGuard API (http://, https:// or socks5://, credentials as user:pass@); defaults to HTTP_PROXY/HTTPS_PROXY/NO_PROXY")
+
+	apiSSHHost       = flag.String("api.ssh.host", "", "SSH server (host[:port]) through which the WANGuard API is reached; the API address is resolved on that server")
+	apiSSHUser       = flag.String("api.ssh.user", "", "User for the SSH tunnel")
+	apiSSHKeyFile    = flag.String("api.ssh.key-file", "", "Unencrypted private key for the SSH tunnel")
+	apiSSHKnownHosts = flag.String("api.ssh.known-hosts", "", "known_hosts file used to verify the SSH server's host key")
+
+	apiTLSCAFile     = flag.String("api.tls.ca-file", "", "PEM bundle of CAs trusted to verify the WANGuard API certificate instead of the system pool")
+	apiTLSCertFile   = flag.String("api.tls.cert-file", "", "PEM client certificate for mutual TLS with the WANGuard API")
+	apiTLSKeyFile    = flag.String("api.tls.key-file", "", "PEM client key for mutual TLS with the WANGuard API")
+	apiTLSServerName = flag.String("api.tls.server-name", "", "Server name used to verify the WANGuard API certificate")
+	apiTLSPins       = flag.String("api.tls.pin-sha256", "", "Comma separated SHA-256 fingerprints of certificates the WANGuard API chain must contain")
+
+	apiRetries          = flag.Int("api.retries", wgc.DefaultRetryPolicy.MaxRetries, "Number of times a failed API request is retried on network errors and 429/502/503/504 responses")
+	apiRetryMin         = flag.Duration("api.retry-backoff", wgc.DefaultRetryPolicy.MinBackoff, "Initial backoff between API request retries, doubled on every attempt")
+	apiRetryMax         = flag.Duration("api.retry-max-backoff", wgc.DefaultRetryPolicy.MaxBackoff, "Maximum backoff between API request retries")
+	apiBreakerThreshold = flag.Int("api.circuit-breaker.failure-threshold", wgc.DefaultCircuitBreakerPolicy.FailureThreshold, "Number of consecutive failed API requests that opens the circuit breaker (0 disables it)")
+	apiBreakerTimeout   = flag.Duration("api.circuit-breaker.open-timeout", wgc.DefaultCircuitBreakerPolicy.OpenTimeout, "How long the circuit breaker stays open before probing the API again")
+
+	apiMaxInFlight    = flag.Int("api.max-in-flight", wgc.DefaultRateLimit.MaxInFlight, "Maximum number of concurrent API requests shared by all collectors (0 = unlimited)")
+	apiRequestsPerSec = flag.Float64("api.requests-per-second", wgc.DefaultRateLimit.RequestsPerSecond, "Maximum sustained API request rate (0 = unlimited)")
+	apiRateLimitBurst = flag.Int("api.rate-limit-burst", wgc.DefaultRateLimit.Burst, "Number of API requests allowed at once before api.requests-per-second applies")
+	apiLimiterMaxWait = flag.Duration("api.rate-limit-max-wait", wgc.DefaultRateLimit.MaxWait, "Longest an API request may queue in the rate limiter before it is rejected (0 = until the scrape times out)")
+	apiMaxRespSize    = flag.Int64("api.max-response-size", wgc.DefaultMaxResponseSize, "Maximum size in bytes of an API response body; larger responses fail with a \"response too large\" error")
+	apiPageSize       = flag.Int("api.page-size", wgc.DefaultPagination.PageSize, "Number of items requested per page from list endpoints such as active anomalies (0 = fetch the whole list at once)")
+	apiMaxItems       = flag.Int("api.max-items", wgc.DefaultPagination.MaxItems, "Maximum number of items read from a list endpoint per scrape (0 = unlimited)")
+	apiCacheTTL       = flag.String("api.cache-ttl", "", "Comma separated endpoint=ttl pairs enabling the API response cache, e.g. license_manager=1h,responses/{id}/actions=10m")
+
 	licenseCollectorEnabled       = flag.Bool("collector.license", true, "Expose license metrics")
 	announcementsCollectorEnabled = flag.Bool("collector.announcements", true, "Expose announcements metrics")
 	anomaliesCollectorEnabled     = flag.Bool("collector.anomalies", true, "Expose anomalies metrics")
@@ -41,8 +90,12 @@ var (
 	firewallRulesCollectorEnabled = flag.Bool("collector.firewall_rules", true, "Expose firewall rules metrics")
 	bgpCollectorEnabled           = flag.Bool("collector.bgp", true, "Expose BGP connector metrics")
 
-	cl             []collectorsList
-	wanguardAPIUp *prometheus.GaugeVec
+	collectorPollInterval = flag.Duration("collector.poll-interval", 0, "Run the collectors in the background at this interval and serve their latest snapshot on /metrics (0 = collect on every scrape)")
+	collectorStaleAfter   = flag.Duration("collector.stale-after", 0, "Age after which a background snapshot is stale and not served anymore (0 = 3 poll intervals)")
+	collectorWorkers      = flag.Int("collector.workers", 4, "Maximum number of collectors run in parallel by a scrape (0 = all at once)")
+	collectorTimeout      = flag.Duration("collector.timeout", 0, "Time a collector may run before it is cancelled and the scrape is served without the rest of its series (0 = until the scrape times out)")
+
+	apiMetrics []prometheus.Collector
 )
 
 func main() {
@@ -52,7 +105,7 @@ func main() {
 	logging.Init("info", "text")
 
 	// Inicializar métricas do client
-	wanguardAPIUp = wgc.InitMetrics()
+	apiMetrics = wgc.InitMetrics()
 
 	if *showVersion {
 		fmt.Println("wanguard_exporter")
@@ -62,62 +115,63 @@ func main() {
 		os.Exit(0)
 	}
 
-	if *apiPassword == "" {
-		*apiPassword = os.Getenv("WANGUARD_PASSWORD")
-		if *apiPassword == "" {
-			logging.Fatal(`Please set to WANGuard API Password!
-		API Password can be set with api.password flag or
-		by setting WANGUARD_PASSWORD environment variable.`)
-		}
-	}
-
-	wgClient, err := wgc.NewClient(*apiAddress, *apiUsername, *apiPassword, *apiInsecure)
+	cfg, err := loadConfig()
 	if err != nil {
-		logging.Fatal("Failed to create WANGuard API client: %v", err)
+		logging.Fatal("%v", err)
 	}
-	cl = []collectorsList{
-		{licenseCollectorEnabled, collectors.NewLicenseCollector(wgClient)},
-		{announcementsCollectorEnabled, collectors.NewAnnouncementsCollector(wgClient)},
-		{anomaliesCollectorEnabled, collectors.NewAnomaliesCollector(wgClient)},
-		{componentsCollectorEnabled, collectors.NewComponentsCollector(wgClient)},
-		{actionsCollectorEnabled, collectors.NewActionsCollector(wgClient)},
-		{sensorsCollectorEnabled, collectors.NewSensorsCollector(wgClient)},
-		{trafficCollectorEnabled, collectors.NewTrafficCollector(wgClient)},
-		{firewallRulesCollectorEnabled, collectors.NewFirewallRulesCollector(wgClient)},
-		{bgpCollectorEnabled, collectors.NewBGPCollector(wgClient)},
+	s, err := buildState(cfg)
+	if err != nil {
+		logging.Fatal("%v", err)
 	}
+	currentState.Store(s)
+	s.client.StartVersionDetection()
+	configReloadSuccess.Set(1)
+	configReloadTimestamp.SetToCurrentTime()
+
+	go reloadOnSIGHUP()
 
 	startServer()
 }
 
+// newCollectorsList creates the collectors reading from api, named and enabled after their collector.* flags
+func newCollectorsList(api wgc.API) []collectorsList {
+	return []collectorsList{
+		{"license", *licenseCollectorEnabled, collectors.NewLicenseCollector(api)},
+		{"announcements", *announcementsCollectorEnabled, collectors.NewAnnouncementsCollector(api)},
+		{"anomalies", *anomaliesCollectorEnabled, collectors.NewAnomaliesCollector(api)},
+		{"components", *componentsCollectorEnabled, collectors.NewComponentsCollector(api)},
+		{"actions", *actionsCollectorEnabled, collectors.NewActionsCollector(api)},
+		{"sensors", *sensorsCollectorEnabled, collectors.NewSensorsCollector(api)},
+		{"traffic", *trafficCollectorEnabled, collectors.NewTrafficCollector(api)},
+		{"firewall_rules", *firewallRulesCollectorEnabled, collectors.NewFirewallRulesCollector(api)},
+		{"bgp", *bgpCollectorEnabled, collectors.NewBGPCollector(api)},
+	}
+}
+
 func startServer() {
 	landingPage := []byte(`<html>
 <head><title>WANGuard Exporter</title></head>
 <body>
 <h1>WANGuard Exporter</h1>
 <p><a href="` + *metricsPath + `">Metrics</a></p>
+<p><a href="` + *probePath + `?target=wanguard.example.com&amp;module=default">Probe a console</a></p>
 </body>
 </html>
 `)
 
-	// Criar registry uma vez
+	// Registry for metrics that do not depend on the scrape context
 	registry := prometheus.NewRegistry()
 
-	// Registrar coletores
-	for _, c := range cl {
-		if *c.enabled {
-			registry.MustRegister(c.collector)
-		}
-	}
-
 	// Registrar métricas do Go
 	registry.MustRegister(prometheus.NewGoCollector())
 	registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
 
-	// Registrar métrica wanguard_api_up
-	if wanguardAPIUp != nil {
-		registry.MustRegister(wanguardAPIUp)
+	// Registrar métricas do client da API (wanguard_api_*)
+	for _, m := range apiMetrics {
+		registry.MustRegister(m)
 	}
+	registry.MustRegister(collectors.InitMetrics()...)
+	registry.MustRegister(configReloadSuccess, configReloadTimestamp)
 
 	logging.Info("Starting WANGuard exporter (Version: %s)", version)
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -126,14 +180,72 @@ func startServer() {
 		}
 	})
 	http.HandleFunc(*metricsPath, func(w http.ResponseWriter, r *http.Request) {
-		promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		state := acquireState()
+		defer state.release()
+
+		selected, err := selectCollectors(state.collectors, func(c collectorsList) bool { return c.enabled }, r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := scrapeContext(r)
+		defer cancel()
+
+		// Collectors are bound to this scrape's context, so they need a registry of their own
+		scrapeRegistry := prometheus.NewRegistry()
+		scrapeRegistry.MustRegister(collectors.WithContext(ctx, state.scheduler(selected)))
+
+		// Gather the collectors first so wanguard_api_up reflects this scrape
+		gatherers := prometheus.Gatherers{scrapeRegistry, registry}
+		promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
 			ErrorLog:      nil,
 			ErrorHandling: promhttp.ContinueOnError}).ServeHTTP(w, r)
 	})
 
+	http.HandleFunc(*probePath, probeHandler)
+	http.HandleFunc("/-/reload", reloadHandler)
+	http.HandleFunc("/-/healthy", healthyHandler)
+	http.HandleFunc("/-/ready", readyHandler)
+
 	logging.Info("Listening for %s on %s", *metricsPath, *listenAddr)
-	serverErr := http.ListenAndServe(*listenAddr, nil)
-	if serverErr != nil {
-		logging.Fatal("Server error: %v", serverErr)
+	serve(&http.Server{
+		Addr:              *listenAddr,
+		ReadHeaderTimeout: *readHeaderTimeout,
+		ReadTimeout:       *readTimeout,
+		WriteTimeout:      *writeTimeout,
+		IdleTimeout:       *idleTimeout,
+		MaxHeaderBytes:    *maxHeaderBytes,
+	})
+}
+
+// scrapeContext derives the context for a scrape from the timeout Prometheus sends in
+// the X-Prometheus-Scrape-Timeout-Seconds header, so that outstanding API calls are
+// cancelled once Prometheus has given up on the scrape. A "Cache-Control: no-cache"
+// request header bypasses the API response cache.
+func scrapeContext(r *http.Request) (context.Context, context.CancelFunc) {
+	ctx := r.Context()
+
+	// Let callers force fresh data from the API with "Cache-Control: no-cache"
+	if strings.Contains(r.Header.Get("Cache-Control"), "no-cache") {
+		ctx = wgc.BypassCache(ctx)
 	}
+
+	header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if header == "" {
+		return context.WithCancel(ctx)
+	}
+
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		logging.Warn("Ignoring invalid X-Prometheus-Scrape-Timeout-Seconds header: %q", header)
+		return context.WithCancel(ctx)
+	}
+
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > *timeoutOffset {
+		timeout -= *timeoutOffset
+	}
+
+	return context.WithTimeout(ctx, timeout)
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScrapeContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		timeout time.Duration // 0 = no deadline
+	}{
+		{"missing", "", 0},
+		{"invalid", "soon", 0},
+		{"zero", "0", 0},
+		{"negative", "-5", 0},
+		{"offset subtracted", "10", 10*time.Second - *timeoutOffset},
+		{"fractional", "1.5", 1500*time.Millisecond - *timeoutOffset},
+		{"smaller than the offset", "0.2", 200 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if test.header != "" {
+			r.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", test.header)
+		}
+
+		start := time.Now()
+		ctx, cancel := scrapeContext(r)
+		deadline, ok := ctx.Deadline()
+		cancel()
+
+		if test.timeout == 0 {
+			if ok {
+				t.Errorf("%s: expected no deadline, got one in %s", test.name, deadline.Sub(start))
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%s: expected a deadline", test.name)
+			continue
+		}
+		// The deadline is taken between start and now
+		if remaining := deadline.Sub(start); remaining < test.timeout || remaining > test.timeout+time.Since(start) {
+			t.Errorf("%s: expected a timeout of %s, got %s", test.name, test.timeout, remaining)
+		}
+	}
+}
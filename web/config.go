@@ -0,0 +1,152 @@
+// Package web serves the exporter's HTTP endpoints with optional TLS, client certificate
+// and basic authentication, configured like the Prometheus exporter-toolkit web
+// configuration file (--web.config.file)
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the content of the web configuration file
+type Config struct {
+	TLSServerConfig *TLSServerConfig `yaml:"tls_server_config"`
+
+	// BasicAuthUsers maps usernames to bcrypt hashes of their passwords
+	BasicAuthUsers map[string]string `yaml:"basic_auth_users"`
+}
+
+// TLSServerConfig holds the TLS settings of the HTTP server
+type TLSServerConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+
+	// ClientAuthType is one of the crypto/tls ClientAuthType names, e.g.
+	// RequireAndVerifyClientCert; it defaults to NoClientCert
+	ClientAuthType string `yaml:"client_auth_type"`
+	ClientCAFile   string `yaml:"client_ca_file"`
+
+	// MinVersion is the oldest accepted TLS version (TLS10 to TLS13), TLS12 by default
+	MinVersion string `yaml:"min_version"`
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                           tls.NoClientCert,
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+var tlsVersions = map[string]uint16{
+	"":      tls.VersionTLS12,
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+// loadedConfig is a validated Config with the files it refers to loaded
+type loadedConfig struct {
+	users map[string][]byte
+
+	// tls is nil when TLS is disabled
+	tls *tls.Config
+
+	// files lists the files the configuration was loaded from, to detect changes
+	files []string
+}
+
+// load reads and validates the web configuration file and the certificates it refers to.
+// Relative paths in the file are resolved against the file's directory.
+func load(filename string) (*loadedConfig, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read web config file: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.UnmarshalStrict(content, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse web config file %s: %w", filename, err)
+	}
+
+	loaded := &loadedConfig{users: make(map[string][]byte), files: []string{filename}}
+
+	for user, hash := range cfg.BasicAuthUsers {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("invalid web config file %s: password of user %q is not a bcrypt hash", filename, user)
+		}
+		loaded.users[user] = []byte(hash)
+	}
+
+	if cfg.TLSServerConfig != nil {
+		if loaded.tls, err = cfg.TLSServerConfig.load(filepath.Dir(filename), loaded); err != nil {
+			return nil, fmt.Errorf("invalid web config file %s: %w", filename, err)
+		}
+	}
+
+	return loaded, nil
+}
+
+func (c *TLSServerConfig) load(dir string, loaded *loadedConfig) (*tls.Config, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("tls_server_config requires cert_file and key_file")
+	}
+
+	clientAuth, ok := clientAuthTypes[c.ClientAuthType]
+	if !ok {
+		return nil, fmt.Errorf("invalid client_auth_type %q", c.ClientAuthType)
+	}
+	minVersion, ok := tlsVersions[c.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("invalid min_version %q", c.MinVersion)
+	}
+
+	certFile, keyFile := resolve(dir, c.CertFile), resolve(dir, c.KeyFile)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	loaded.files = append(loaded.files, certFile, keyFile)
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   clientAuth,
+		MinVersion:   minVersion,
+	}
+
+	verifies := clientAuth == tls.VerifyClientCertIfGiven || clientAuth == tls.RequireAndVerifyClientCert
+	switch {
+	case c.ClientCAFile != "":
+		caFile := resolve(dir, c.ClientCAFile)
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", caFile)
+		}
+		config.ClientCAs = pool
+		loaded.files = append(loaded.files, caFile)
+	case verifies:
+		return nil, fmt.Errorf("client_auth_type %s requires client_ca_file", c.ClientAuthType)
+	}
+
+	return config, nil
+}
+
+func resolve(dir, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(dir, file)
+}
@@ -0,0 +1,223 @@
+package web
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tomvil/wanguard_exporter/logging"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// checkInterval bounds how often the web configuration file and the files it refers to
+// are checked for changes
+const checkInterval = 5 * time.Second
+
+// ListenAndServe listens on server.Addr and serves requests like Serve
+func ListenAndServe(server *http.Server, configFile string) error {
+	addr := server.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return Serve(listener, server, configFile)
+}
+
+// Serve serves requests on listener according to the web configuration file. Without
+// a file it behaves like server.Serve. The file and the certificates it refers to are
+// re-read when they change, so renewed certificates and changed users are picked up
+// without a restart; enabling or disabling TLS requires one.
+func Serve(listener net.Listener, server *http.Server, configFile string) error {
+	if configFile == "" {
+		return server.Serve(listener)
+	}
+
+	wc, err := newWebConfig(configFile)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	handler := server.Handler
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	server.Handler = wc.authenticate(handler)
+
+	if wc.current.tls == nil {
+		logging.Info("TLS is disabled")
+		return server.Serve(listener)
+	}
+
+	logging.Info("TLS is enabled")
+	server.TLSConfig = &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return wc.get().tls, nil
+		},
+		// Not used while GetConfigForClient returns a config, but required by ServeTLS
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &wc.get().tls.Certificates[0], nil
+		},
+	}
+	return server.ServeTLS(listener, "", "")
+}
+
+// webConfig holds the current web configuration, reloading it when its files change
+type webConfig struct {
+	filename string
+
+	mu        sync.Mutex
+	current   *loadedConfig
+	versions  map[string]fileVersion
+	lastCheck time.Time
+
+	// authCache remembers successfully verified credentials, so that bcrypt only runs
+	// once per user and password instead of on every scrape
+	authCache sync.Map
+}
+
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func newWebConfig(filename string) (*webConfig, error) {
+	current, err := load(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webConfig{
+		filename:  filename,
+		current:   current,
+		versions:  stat(current.files),
+		lastCheck: time.Now(),
+	}, nil
+}
+
+// get returns the current configuration, reloading it first if one of its files
+// changed. Files are stat'ed at most once per checkInterval.
+func (w *webConfig) get() *loadedConfig {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if time.Since(w.lastCheck) < checkInterval {
+		return w.current
+	}
+	w.lastCheck = time.Now()
+
+	versions := stat(w.current.files)
+	if equalVersions(versions, w.versions) {
+		return w.current
+	}
+
+	reloaded, err := load(w.filename)
+	if err == nil && (reloaded.tls == nil) != (w.current.tls == nil) {
+		err = errors.New("enabling or disabling TLS requires a restart")
+	}
+	if err != nil {
+		logging.Error("Failed to reload web config file, keeping the previous one: %v", err)
+		// Only retry once the files change again
+		w.versions = versions
+		return w.current
+	}
+
+	w.current = reloaded
+	w.versions = stat(reloaded.files)
+	w.authCache.Range(func(key, _ interface{}) bool {
+		w.authCache.Delete(key)
+		return true
+	})
+	logging.Info("Reloaded web config file %s", w.filename)
+
+	return w.current
+}
+
+func stat(files []string) map[string]fileVersion {
+	versions := make(map[string]fileVersion, len(files))
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			versions[file] = fileVersion{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return versions
+}
+
+func equalVersions(a, b map[string]fileVersion) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, version := range a {
+		if b[file] != version {
+			return false
+		}
+	}
+	return true
+}
+
+// unauthenticatedPaths are served without basic authentication, so that the health checks
+// of container runtimes and orchestrators work without credentials
+var unauthenticatedPaths = map[string]bool{
+	"/-/healthy": true,
+	"/-/ready":   true,
+}
+
+// authenticate requires basic authentication for all requests but the health checks when
+// users are configured
+func (w *webConfig) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		cfg := w.get()
+		if len(cfg.users) == 0 || unauthenticatedPaths[r.URL.Path] {
+			next.ServeHTTP(rw, r)
+			return
+		}
+
+		user, password, ok := r.BasicAuth()
+		if ok && w.validUser(cfg, user, password) {
+			next.ServeHTTP(rw, r)
+			return
+		}
+
+		rw.Header().Set("WWW-Authenticate", `Basic realm="wanguard_exporter", charset="UTF-8"`)
+		http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// validUser checks password against the bcrypt hash of user. Unknown users are checked
+// against a dummy hash, so that response times do not reveal which users exist.
+func (w *webConfig) validUser(cfg *loadedConfig, user, password string) bool {
+	hash, known := cfg.users[user]
+	if !known {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("wanguard_exporter"), bcrypt.DefaultCost)
+		})
+		hash = dummyHash
+	}
+
+	key := sha256.Sum256([]byte(user + "\x00" + password + "\x00" + string(hash)))
+	if _, ok := w.authCache.Load(key); ok {
+		return true
+	}
+
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || !known {
+		return false
+	}
+	w.authCache.Store(key, struct{}{})
+
+	return true
+}
@@ -0,0 +1,285 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testCA issues certificates for the tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for name, valid for servers and clients
+func (ca *testCA) issue(t *testing.T, name string, serial int64) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, dir, name string, content []byte) string {
+	t.Helper()
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// serve starts Serve with configFile and returns the address it listens on
+func serve(t *testing.T, configFile string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})}
+	t.Cleanup(func() { server.Close() })
+
+	go func() {
+		_ = Serve(listener, server, configFile)
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configFile := writeFile(t, t.TempDir(), "web.yml", []byte("basic_auth_users:\n  prometheus: "+string(hash)+"\n"))
+	addr := serve(t, configFile)
+
+	tests := []struct {
+		user, password string
+		auth           bool
+		code           int
+	}{
+		{"", "", false, http.StatusUnauthorized},
+		{"prometheus", "wrong", true, http.StatusUnauthorized},
+		{"unknown", "secret", true, http.StatusUnauthorized},
+		{"prometheus", "secret", true, http.StatusOK},
+		{"prometheus", "secret", true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodGet, "http://"+addr+"/metrics", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.auth {
+			req.SetBasicAuth(tt.user, tt.password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.code {
+			t.Errorf("%s/%s: expected %d, got %d", tt.user, tt.password, tt.code, resp.StatusCode)
+		}
+		if tt.code == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+			t.Error("Expected WWW-Authenticate header")
+		}
+	}
+
+	// Health checks need no credentials
+	for _, path := range []string{"/-/healthy", "/-/ready"} {
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: expected %d, got %d", path, http.StatusOK, resp.StatusCode)
+		}
+	}
+}
+
+func TestTLSWithClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "localhost", 2)
+	clientCert, clientKey := ca.issue(t, "prometheus", 3)
+
+	writeFile(t, dir, "server.crt", serverCert)
+	writeFile(t, dir, "server.key", serverKey)
+	writeFile(t, dir, "ca.crt", ca.pem)
+	configFile := writeFile(t, dir, "web.yml", []byte(`tls_server_config:
+  cert_file: server.crt
+  key_file: server.key
+  client_auth_type: RequireAndVerifyClientCert
+  client_ca_file: ca.crt
+`))
+	addr := serve(t, configFile)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	pair, err := tls.X509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{pair}}}}
+	resp, err := withCert.Get("https://" + addr + "/metrics")
+	if err != nil {
+		t.Fatalf("Expected request with client certificate to succeed, got %v", err)
+	}
+	resp.Body.Close()
+
+	withoutCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	if resp, err := withoutCert.Get("https://" + addr + "/metrics"); err == nil {
+		resp.Body.Close()
+		t.Error("Expected request without client certificate to fail")
+	}
+}
+
+func TestCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	cert, key := ca.issue(t, "localhost", 2)
+	certFile := writeFile(t, dir, "server.crt", cert)
+	keyFile := writeFile(t, dir, "server.key", key)
+	configFile := writeFile(t, dir, "web.yml", []byte("tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n"))
+
+	wc, err := newWebConfig(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := servedSerial(t, wc); got != 2 {
+		t.Fatalf("Unexpected initial certificate serial %d", got)
+	}
+
+	renewed, renewedKey := ca.issue(t, "localhost", 42)
+	writeFile(t, dir, "server.crt", renewed)
+	writeFile(t, dir, "server.key", renewedKey)
+	future := time.Now().Add(time.Minute)
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Pretend the check interval passed
+	wc.lastCheck = time.Time{}
+	if got := servedSerial(t, wc); got != 42 {
+		t.Errorf("Expected renewed certificate, got serial %d", got)
+	}
+
+	// A broken configuration keeps the previous one
+	writeFile(t, dir, "web.yml", []byte("tls_server_config:\n  cert_file: missing.crt\n  key_file: server.key\n"))
+	if err := os.Chtimes(configFile, future.Add(time.Minute), future.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	wc.lastCheck = time.Time{}
+	if wc.get().tls == nil {
+		t.Error("Expected the previous TLS configuration to be kept")
+	}
+}
+
+// servedSerial returns the serial number of the certificate wc currently serves
+func servedSerial(t *testing.T, wc *webConfig) int64 {
+	t.Helper()
+	cert, err := x509.ParseCertificate(wc.get().tls.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.SerialNumber.Int64()
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"unknown key":      "basic_auth_user:\n  a: b\n",
+		"plain password":   "basic_auth_users:\n  prometheus: secret\n",
+		"missing key file": "tls_server_config:\n  cert_file: server.crt\n",
+		"missing files":    "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n",
+	}
+	for name, content := range tests {
+		if _, err := load(writeFile(t, dir, "web.yml", []byte(content))); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	ca := newTestCA(t)
+	cert, key := ca.issue(t, "localhost", 2)
+	writeFile(t, dir, "server.crt", cert)
+	writeFile(t, dir, "server.key", key)
+	for name, extra := range map[string]string{
+		"client auth type": "  client_auth_type: Always\n",
+		"missing CA":       "  client_auth_type: RequireAndVerifyClientCert\n",
+		"min version":      "  min_version: SSL3\n",
+	} {
+		content := "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n" + extra
+		if _, err := load(writeFile(t, dir, "web.yml", []byte(content))); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}